@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"hypr-exiled/internal/models"
+	"hypr-exiled/internal/storage"
+	"hypr-exiled/pkg/logger"
+)
+
+// handleHistory prints the trade history ledger, optionally filtered, followed
+// by the totals of completed sales per currency.
+func handleHistory(log *logger.Logger, configPath, league, item, since, until string) {
+	_, cleanup, err := initializeCommon(log, configPath)
+	if err != nil {
+		log.Error("Initialization failed", err)
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return
+	}
+	defer cleanup()
+
+	filter := storage.HistoryFilter{League: league, Item: item}
+	if filter.Since, err = parseTimeFlag(since, time.Local); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: invalid -since: %v\n", err)
+		return
+	}
+	if filter.Until, err = parseTimeFlag(until, time.Local); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: invalid -until: %v\n", err)
+		return
+	}
+
+	db, err := storage.New()
+	if err != nil {
+		log.Error("Failed to open storage", err)
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return
+	}
+	defer db.Close()
+
+	history, err := db.GetHistory(filter)
+	if err != nil {
+		log.Error("Failed to read trade history", err)
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return
+	}
+
+	if len(history) == 0 {
+		fmt.Println("No trades in history")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CLOSED\tOUTCOME\tSIDE\tPLAYER\tITEM\tPRICE\tLEAGUE\tDURATION")
+
+	sales := make(map[string]float64)
+	for _, entry := range history {
+		side := "buy"
+		if entry.IsIncoming() {
+			side = "sell"
+			if entry.Outcome == models.TradeStatusCompleted {
+				sales[models.NormalizeCurrency(entry.CurrencyType)] += entry.CurrencyAmount
+			}
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t@%s\t%s\t%g %s\t%s\t%s\n",
+			entry.ClosedAt.Local().Format("2006/01/02 15:04"),
+			entry.Outcome,
+			side,
+			entry.PlayerName,
+			entry.ItemDescription(),
+			entry.CurrencyAmount,
+			entry.CurrencyType,
+			entry.League,
+			entry.Duration.Round(time.Second))
+	}
+	w.Flush()
+
+	if len(sales) > 0 {
+		fmt.Printf("\nSold: %s\n", formatEarned(sales))
+	}
+}
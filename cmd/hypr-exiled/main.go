@@ -5,16 +5,23 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog"
 
 	"hypr-exiled/internal/app"
 	"hypr-exiled/internal/ipc"
+	"hypr-exiled/internal/models"
+	poe_log "hypr-exiled/internal/poe/log"
+	"hypr-exiled/internal/trade_manager"
 	"hypr-exiled/pkg/config"
 	"hypr-exiled/pkg/global"
 	"hypr-exiled/pkg/logger"
 	"hypr-exiled/pkg/notify"
+	"hypr-exiled/pkg/profile"
 )
 
 //go:embed assets/*
@@ -25,6 +32,7 @@ func main() {
 	_ = godotenv.Load()
 	
 	configPath := flag.String("config", "", "path to config file")
+	profileName := flag.String("profile", "", "use a separate config, database, log and socket under this profile name")
 	debug := flag.Bool("debug", false, "enable debug logging")
 	showTrades := flag.Bool("showTrades", false, "show the trades UI")
 	hideout := flag.Bool("hideout", false, "go to hideout")
@@ -32,8 +40,30 @@ func main() {
     search := flag.Bool("search", false, "search item on PoE 2 trade site")
     price := flag.Bool("price", false, "check average price for item via API")
     research := flag.Bool("research", false, "research high-priced items for the same type and aggregate impactful stats")
+	showStatus := flag.Bool("status", false, "print the current zone and AFK/busy state")
+	busy := flag.String("busy", "", "set the busy flag that enables auto-replies: on, off or toggle")
+	replay := flag.String("replay", "", "replay a saved Client.txt through the configured triggers")
+	speed := flag.Float64("speed", 0, "replay speed multiplier (1 = real time, 0 = no delay)")
+	since := flag.String("since", "", "only replay lines or list history at or after this time (YYYY/MM/DD [HH:MM[:SS]])")
+	until := flag.String("until", "", "only replay lines or list history at or before this time (YYYY/MM/DD [HH:MM[:SS]])")
+	history := flag.Bool("history", false, "list closed trades from the trade history")
+	showStats := flag.Bool("stats", false, "report sales statistics from the trade history")
+	asJSON := flag.Bool("json", false, "print -stats as JSON")
+	league := flag.String("league", "", "only use history of this league")
+	item := flag.String("item", "", "only use history of items containing this text")
+	block := flag.String("block", "", "hide and mute the whispers of this player")
+	allow := flag.String("allow", "", "mark this player's whispers and play the friend sound")
+	unblock := flag.String("unblock", "", "take this player off the block- and allowlist")
+	players := flag.Bool("players", false, "list the blocked and allowed players")
 	flag.Parse()
 
+	// The profile decides where the log, config, database and socket live,
+	// so it has to be selected before anything is opened
+	if err := profile.Set(*profileName); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Initialize logger
 	logLevel := zerolog.InfoLevel
 	if *debug {
@@ -64,6 +94,24 @@ func main() {
         handlePrice(log, *configPath)
     case *research:
         handleResearch(log, *configPath)
+	case *showStatus:
+		handleStatus(log, *configPath)
+	case *busy != "":
+		handleBusy(log, *configPath, *busy)
+	case *block != "":
+		handlePlayerList(log, *configPath, "block", *block)
+	case *allow != "":
+		handlePlayerList(log, *configPath, "allow", *allow)
+	case *unblock != "":
+		handlePlayerList(log, *configPath, "unblock", *unblock)
+	case *players:
+		handlePlayers(log, *configPath)
+	case *showStats:
+		handleStats(log, *configPath, *league, *item, *since, *until, *asJSON)
+	case *history:
+		handleHistory(log, *configPath, *league, *item, *since, *until)
+	case *replay != "":
+		handleReplay(log, *configPath, *replay, *speed, *since, *until)
     default:
         startBackgroundService(log, *configPath)
     }
@@ -122,6 +170,95 @@ func startBackgroundService(log *logger.Logger, configPath string) {
 	}
 }
 
+// handleReplay feeds a saved Client.txt through the configured triggers without
+// the game running, showing the notifications the service would send.
+func handleReplay(log *logger.Logger, configPath, path string, speed float64, since, until string) {
+	_, cleanup, err := initializeCommon(log, configPath)
+	if err != nil {
+		log.Error("Initialization failed", err)
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return
+	}
+	defer cleanup()
+
+	opts := poe_log.ReplayOptions{Speed: speed}
+	// Log timestamps are compared as written, without a time zone
+	if opts.Since, err = parseTimeFlag(since, time.UTC); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: invalid -since: %v\n", err)
+		return
+	}
+	if opts.Until, err = parseTimeFlag(until, time.UTC); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: invalid -until: %v\n", err)
+		return
+	}
+
+	watcher := poe_log.NewReplayWatcher(func(entry models.TradeEntry) {
+		fmt.Printf("%s [%s] @%s %s for %g %s\n",
+			entry.Timestamp.Format("2006/01/02 15:04:05"),
+			entry.TriggerType,
+			entry.PlayerName,
+			entry.ItemDescription(),
+			entry.CurrencyAmount,
+			entry.CurrencyType)
+
+		if entry.IsIncoming() {
+			if notifier := global.GetSoundNotifier(); notifier != nil {
+				if err := notifier.PlayTradeSound(); err != nil {
+					log.Error("Failed to play trade sound", err)
+				}
+			}
+		}
+		global.GetNotifier().Show(trade_manager.NotificationMessage(entry), notify.Info)
+	})
+	watcher.SetEventHandler(func(event models.LogEvent) {
+		fmt.Printf("%s [%s] @%s\n",
+			event.Timestamp.Format("2006/01/02 15:04:05"),
+			event.Type,
+			event.PlayerName)
+	})
+
+	// Allow interrupting long real-time replays
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		watcher.Stop()
+	}()
+
+	stats, err := watcher.Replay(path, opts)
+	if err != nil {
+		log.Error("Replay failed", err, "path", path)
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return
+	}
+
+	fmt.Printf("\nReplayed %d lines (%d in range), %d trade events triggered\n",
+		stats.Lines, stats.InRange, stats.Triggers)
+}
+
+// parseTimeFlag parses a -since/-until value in the timestamp format of
+// Client.txt, in the given location.
+func parseTimeFlag(value string, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	layouts := []string{
+		"2006/01/02 15:04:05",
+		"2006/01/02 15:04",
+		"2006/01/02",
+		"2006-01-02 15:04:05",
+		"2006-01-02T15:04:05",
+		"2006-01-02",
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q, expected YYYY/MM/DD [HH:MM[:SS]]", value)
+}
+
 func handleHideout(log *logger.Logger, configPath string) {
 	_, cleanup, err := initializeCommon(log, configPath)
 	if err != nil {
@@ -147,6 +284,70 @@ func handleHideout(log *logger.Logger, configPath string) {
 	log.Info("Hideout command executed via IPC")
 }
 
+// handleStatus prints the zone and availability tracked by the background service.
+func handleStatus(log *logger.Logger, configPath string) {
+	_, cleanup, err := initializeCommon(log, configPath)
+	if err != nil {
+		log.Error("Initialization failed", err)
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return
+	}
+	defer cleanup()
+
+	resp, err := ipc.SendCommand("status")
+	if err != nil {
+		log.Error("Status command failed", err)
+		fmt.Fprintln(os.Stderr, "ERROR: failed to contact service, is it running?")
+		return
+	}
+
+	if resp.Status != "success" {
+		log.Error("Status command failed", fmt.Errorf("message: %s", resp.Message))
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", resp.Message)
+		return
+	}
+
+	fmt.Println(resp.Message)
+}
+
+// handleBusy switches the busy flag of the background service.
+func handleBusy(log *logger.Logger, configPath string, mode string) {
+	commands := map[string]string{
+		"on":     "busyOn",
+		"off":    "busyOff",
+		"toggle": "busyToggle",
+	}
+	command, ok := commands[mode]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ERROR: invalid -busy value %q, expected on, off or toggle\n", mode)
+		return
+	}
+
+	_, cleanup, err := initializeCommon(log, configPath)
+	if err != nil {
+		log.Error("Initialization failed", err)
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return
+	}
+	defer cleanup()
+
+	resp, err := ipc.SendCommand(command)
+	if err != nil {
+		log.Error("Busy command failed", err)
+		global.GetNotifier().Show("Failed to contact service", notify.Error)
+		return
+	}
+
+	if resp.Status != "success" {
+		log.Error("Busy command failed", fmt.Errorf("message: %s", resp.Message))
+		global.GetNotifier().Show(resp.Message, notify.Error)
+		return
+	}
+
+	global.GetNotifier().Show(resp.Message, notify.Info)
+	log.Info("Busy flag updated via IPC", "mode", mode)
+}
+
 func handleKingsmarch(log *logger.Logger, configPath string) {
 	_, cleanup, err := initializeCommon(log, configPath)
 	if err != nil {
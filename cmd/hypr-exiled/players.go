@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"hypr-exiled/internal/ipc"
+	"hypr-exiled/pkg/logger"
+)
+
+// handlePlayerList puts a player on the block- or allowlist of the background
+// service, or takes them off with "unblock".
+func handlePlayerList(log *logger.Logger, configPath, command, player string) {
+	_, cleanup, err := initializeCommon(log, configPath)
+	if err != nil {
+		log.Error("Initialization failed", err)
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return
+	}
+	defer cleanup()
+
+	resp, err := ipc.SendCommand(command, player)
+	if err != nil {
+		log.Error("Player list command failed", err)
+		fmt.Fprintln(os.Stderr, "ERROR: failed to contact service, is it running?")
+		return
+	}
+
+	if resp.Status != "success" {
+		log.Error("Player list command failed", fmt.Errorf("message: %s", resp.Message))
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", resp.Message)
+		return
+	}
+
+	fmt.Println(resp.Message)
+}
+
+// handlePlayers prints the block- and allowlist.
+func handlePlayers(log *logger.Logger, configPath string) {
+	_, cleanup, err := initializeCommon(log, configPath)
+	if err != nil {
+		log.Error("Initialization failed", err)
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return
+	}
+	defer cleanup()
+
+	resp, err := ipc.SendCommand("players")
+	if err != nil {
+		log.Error("Players command failed", err)
+		fmt.Fprintln(os.Stderr, "ERROR: failed to contact service, is it running?")
+		return
+	}
+
+	if resp.Status != "success" {
+		log.Error("Players command failed", fmt.Errorf("message: %s", resp.Message))
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", resp.Message)
+		return
+	}
+
+	if len(resp.Players) == 0 {
+		fmt.Println("No players on the block- or allowlist")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LIST\tPLAYER\tADDED")
+	for _, player := range resp.Players {
+		fmt.Fprintf(w, "%s\t@%s\t%s\n",
+			player.List,
+			player.Name,
+			player.AddedAt.Local().Format("2006/01/02 15:04"))
+	}
+	w.Flush()
+}
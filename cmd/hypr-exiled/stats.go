@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"hypr-exiled/internal/stats"
+	"hypr-exiled/internal/storage"
+	"hypr-exiled/pkg/logger"
+)
+
+// handleStats prints sales analytics over the trade history, as tables or
+// as JSON for dashboards.
+func handleStats(log *logger.Logger, configPath, league, item, since, until string, asJSON bool) {
+	_, cleanup, err := initializeCommon(log, configPath)
+	if err != nil {
+		log.Error("Initialization failed", err)
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return
+	}
+	defer cleanup()
+
+	filter := storage.HistoryFilter{League: league, Item: item}
+	if filter.Since, err = parseTimeFlag(since, time.Local); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: invalid -since: %v\n", err)
+		return
+	}
+	if filter.Until, err = parseTimeFlag(until, time.Local); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: invalid -until: %v\n", err)
+		return
+	}
+
+	db, err := storage.New()
+	if err != nil {
+		log.Error("Failed to open storage", err)
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return
+	}
+	defer db.Close()
+
+	history, err := db.GetHistory(filter)
+	if err != nil {
+		log.Error("Failed to read trade history", err)
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return
+	}
+
+	report := stats.Compute(history)
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Error("Failed to encode stats", err)
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		}
+		return
+	}
+
+	if report.Total.Trades == 0 {
+		fmt.Println("No sales in history")
+		return
+	}
+
+	printSummaries(os.Stdout, "Total", []stats.Summary{report.Total})
+	printSummaries(os.Stdout, "Sessions", report.Sessions)
+	printSummaries(os.Stdout, "Days", report.Days)
+	printSummaries(os.Stdout, "Leagues", report.Leagues)
+
+	if len(report.Total.TopItems) > 0 {
+		fmt.Println("Top items")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, top := range report.Total.TopItems {
+			fmt.Fprintf(w, "  %s\t%d\n", top.Item, top.Sales)
+		}
+		w.Flush()
+	}
+}
+
+func printSummaries(out io.Writer, title string, summaries []stats.Summary) {
+	fmt.Fprintln(out, title)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  \tSALES\tEARNED\tAVG TIME\tDECLINED\tNO-SHOWS")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "  %s\t%d/%d\t%s\t%s\t%.0f%%\t%.0f%%\n",
+			s.Label,
+			s.Sales,
+			s.Trades,
+			formatEarned(s.Earned),
+			s.AvgCompletion().Round(time.Second),
+			s.DeclineRate*100,
+			s.NoShowRate*100)
+	}
+	w.Flush()
+	fmt.Fprintln(out)
+}
+
+// formatEarned lists the currency totals, e.g. "12 divine, 340 chaos".
+func formatEarned(earned map[string]float64) string {
+	if len(earned) == 0 {
+		return "-"
+	}
+
+	currencies := make([]string, 0, len(earned))
+	for currency := range earned {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	totals := make([]string, len(currencies))
+	for i, currency := range currencies {
+		totals[i] = fmt.Sprintf("%g %s", earned[currency], currency)
+	}
+	return strings.Join(totals, ", ")
+}
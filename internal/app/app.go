@@ -83,6 +83,8 @@ func NewHyprExiled() (*HyprExiled, error) {
 		return nil, fmt.Errorf("log path resolution failed: %w", err)
 	}
 
+	logWatcher.SetEventHandler(tradeManager.HandleLogEvent)
+
 	log.Debug("Resolved initial log path", "app_id", initialAppID, "game", config.GameNameByAppID(initialAppID), "path", initialPath)
 	logWatcher.SetPathOverride(initialPath)
 
@@ -116,6 +118,9 @@ func (p *HyprExiled) Run() error {
 	log.Info("Starting IPC socket server")
 	go ipc.StartSocketServer(p.TradeManager, p.input)
 
+	log.Info("Starting trade expiry worker")
+	p.TradeManager.Start()
+
 	if err := notifier.Show("Hypr Exiled started", notify.Info); err != nil {
 		log.Error("Startup notification failed",
 			err,
@@ -151,6 +156,14 @@ func (p *HyprExiled) Stop() error {
 		p.poeLogWatcher.Stop()
 	}
 
+	if p.TradeManager != nil {
+		log.Debug("Stopping trade manager")
+		p.TradeManager.Stop()
+		if err := p.TradeManager.Close(); err != nil {
+			log.Error("Failed to close trade manager", err)
+		}
+	}
+
 	if p.detector != nil {
 		log.Debug("Stopping window detector")
 		_ = p.detector.Stop()
@@ -226,6 +239,7 @@ func (p *HyprExiled) handleAppIDChanges() {
 			continue
 		}
 		nw.SetPathOverride(newPath)
+		nw.SetEventHandler(p.TradeManager.HandleLogEvent)
 		p.poeLogWatcher = nw
 
 		go func() {
@@ -14,6 +14,7 @@ import (
     "sort"
     "strconv"
     "strings"
+	"sync"
 	"time"
 
 	"github.com/go-vgo/robotgo"
@@ -33,6 +34,9 @@ type Input struct {
 	detector      *window.Detector
 	log           *logger.Logger
 	notifier      *notify.NotifyService
+
+	// Serializes keystrokes, so commands sent at the same time don't mix
+	mu sync.Mutex
 }
 
 // Typing/timing parameters (tune as needed; consider moving to config later).
@@ -62,6 +66,9 @@ func NewInput(detector *window.Detector) (*Input, error) {
 func (i *Input) ExecutePoECommands(commands []string) error {
 	cfg := global.GetConfig()
 
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
 	if !i.detector.IsActive() {
 		return fmt.Errorf("%s needs to be running", cfg.GameNameByAppID(i.detector.ActiveAppID()))
 	}
@@ -5,10 +5,12 @@ import (
 	"net"
 
 	"hypr-exiled/pkg/global"
+	"hypr-exiled/pkg/profile"
 )
 
-func SendCommand(command string) (Response, error) {
+func SendCommand(command string, args ...string) (Response, error) {
 	log := global.GetLogger()
+	socketPath := profile.SocketPath()
 
 	log.Debug("Attempting to connect to socket server", "path", socketPath)
 
@@ -21,7 +23,7 @@ func SendCommand(command string) (Response, error) {
 
 	log.Debug("Connected to socket server", "remote_addr", conn.RemoteAddr())
 
-	req := Request{Command: command}
+	req := Request{Command: command, Args: args}
 	encoder := json.NewEncoder(conn)
 	if err := encoder.Encode(req); err != nil {
 		log.Error("Failed to encode request", err)
@@ -8,25 +8,30 @@ import (
 	"path/filepath"
 
 	"hypr-exiled/internal/input"
+	"hypr-exiled/internal/models"
+	"hypr-exiled/internal/status"
 	"hypr-exiled/internal/trade_manager"
 	"hypr-exiled/pkg/global"
+	"hypr-exiled/pkg/profile"
 )
 
-const socketPath = "/tmp/hypr-exiled.sock"
-
 type Request struct {
-	Command string `json:"command"`
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
 }
 
 type Response struct {
-    Status    string                 `json:"status"`
-    Message   string                 `json:"message"`
-    PriceData map[string]interface{} `json:"price_data,omitempty"`
-    ResearchData map[string]interface{} `json:"research_data,omitempty"`
+	Status       string                 `json:"status"`
+	Message      string                 `json:"message"`
+	PriceData    map[string]interface{} `json:"price_data,omitempty"`
+	ResearchData map[string]interface{} `json:"research_data,omitempty"`
+	StatusData   *status.Status         `json:"status_data,omitempty"`
+	Players      []models.ListedPlayer  `json:"players,omitempty"`
 }
 
 func StartSocketServer(tradeManager *trade_manager.TradeManager, input *input.Input) {
 	log := global.GetLogger()
+	socketPath := profile.SocketPath()
 
 	// Remove the socket file if it already exists
 	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
@@ -118,6 +123,47 @@ func handleConnection(conn net.Conn, tradeManager *trade_manager.TradeManager, i
 				Message: "Warped to kingsmarch",
 			}
 		}
+	case "status":
+		log.Debug("Handling status request")
+		current := tradeManager.Status().Get()
+		resp = Response{
+			Status:     "success",
+			Message:    current.Summary(),
+			StatusData: &current,
+		}
+	case "busyOn", "busyOff", "busyToggle":
+		log.Debug("Handling busy request", "command", req.Command)
+		tracker := tradeManager.Status()
+		switch req.Command {
+		case "busyOn":
+			tracker.SetBusy(true)
+		case "busyOff":
+			tracker.SetBusy(false)
+		default:
+			tracker.ToggleBusy()
+		}
+
+		busy := tracker.Get().Busy
+		log.Info("Busy flag updated", "busy", busy)
+		message := "Busy mode off"
+		if busy {
+			message = "Busy mode on, incoming whispers get an auto-reply"
+		}
+		resp = Response{Status: "success", Message: message}
+	case "block", "allow", "unblock":
+		log.Debug("Handling player list request", "command", req.Command, "args", req.Args)
+		resp = handlePlayerList(tradeManager, req)
+	case "players":
+		log.Debug("Handling players request")
+		if players, err := tradeManager.ListedPlayers(); err != nil {
+			resp = Response{Status: "error", Message: err.Error()}
+		} else {
+			resp = Response{
+				Status:  "success",
+				Message: fmt.Sprintf("%d listed players", len(players)),
+				Players: players,
+			}
+		}
 	case "search":
 		log.Debug("Handling search request")
 		if err := input.ExecuteSearch(); err != nil {
@@ -222,3 +268,34 @@ func handleConnection(conn net.Conn, tradeManager *trade_manager.TradeManager, i
 		log.Debug("Response sent successfully", "status", resp.Status)
 	}
 }
+
+// handlePlayerList puts the player named in the request on the block- or
+// allowlist, or takes them off.
+func handlePlayerList(tradeManager *trade_manager.TradeManager, req Request) Response {
+	if len(req.Args) != 1 || req.Args[0] == "" {
+		return Response{Status: "error", Message: req.Command + " needs a player name"}
+	}
+	player := req.Args[0]
+
+	switch req.Command {
+	case "block":
+		if err := tradeManager.BlockPlayer(player); err != nil {
+			return Response{Status: "error", Message: err.Error()}
+		}
+		return Response{Status: "success", Message: fmt.Sprintf("Blocked @%s", player)}
+	case "allow":
+		if err := tradeManager.AllowPlayer(player); err != nil {
+			return Response{Status: "error", Message: err.Error()}
+		}
+		return Response{Status: "success", Message: fmt.Sprintf("Allowed @%s", player)}
+	default:
+		removed, err := tradeManager.UnlistPlayer(player)
+		if err != nil {
+			return Response{Status: "error", Message: err.Error()}
+		}
+		if !removed {
+			return Response{Status: "error", Message: fmt.Sprintf("@%s is not on a list", player)}
+		}
+		return Response{Status: "success", Message: fmt.Sprintf("Removed @%s from the lists", player)}
+	}
+}
@@ -1,12 +1,46 @@
 package models
 
 import (
+	"fmt"
 	"regexp"
+	"strings"
 	"time"
 )
 
+// TradeStatus is the lifecycle state of a trade
+type TradeStatus string
+
+const (
+	TradeStatusNew       TradeStatus = "new"
+	TradeStatusInvited   TradeStatus = "invited"
+	TradeStatusInHideout TradeStatus = "in_hideout"
+	TradeStatusTrading   TradeStatus = "trading"
+	TradeStatusCompleted TradeStatus = "completed"
+	TradeStatusDeclined  TradeStatus = "declined"
+	TradeStatusExpired   TradeStatus = "expired"
+)
+
+// OpenTradeStatuses are the states of trades that still need attention.
+var OpenTradeStatuses = []TradeStatus{
+	TradeStatusNew,
+	TradeStatusInvited,
+	TradeStatusInHideout,
+	TradeStatusTrading,
+}
+
+// IsOpen reports whether the trade has not been closed yet.
+func (s TradeStatus) IsOpen() bool {
+	for _, open := range OpenTradeStatuses {
+		if s == open {
+			return true
+		}
+	}
+	return false
+}
+
 // TradeEntry represents a trade-related log entry
 type TradeEntry struct {
+	ID             int64 // database id, zero until the trade is stored
 	Timestamp      time.Time
 	TriggerType    string
 	PlayerName     string
@@ -21,6 +55,151 @@ type TradeEntry struct {
 	}
 	Message      string
 	IsBuyRequest bool
+	// Bulk trades (currency exchange whispers) swap ItemAmount of ItemName
+	// for CurrencyAmount of CurrencyType and have no stash position.
+	IsBulk     bool
+	ItemAmount float64
+	// Optional listing qualifiers; zero when the whisper does not carry them
+	ItemLevel   int
+	ItemQuality int
+	Note        string // free text the buyer appended to the whisper
+	// WhileMapping is set for incoming trades that arrived while we were in a map
+	WhileMapping bool
+	// BuyerArrived is set once the buyer of an incoming trade joined our area
+	BuyerArrived bool
+	// Pings counts how often the buyer sent this whisper, 1 for the first
+	Pings      int
+	PlayerList PlayerList // list the player is on, empty if none
+	Status     TradeStatus
+	CreatedAt  time.Time // when the trade was stored
+}
+
+// PlayerList is a list players can be put on to change how their whispers are
+// handled
+type PlayerList string
+
+const (
+	PlayerBlocked PlayerList = "blocked" // whispers are stored but hidden and muted
+	PlayerAllowed PlayerList = "allowed" // whispers are marked and get their own sound
+)
+
+// ListedPlayer is a player on the block- or allowlist
+type ListedPlayer struct {
+	Name    string     `json:"name"`
+	List    PlayerList `json:"list"`
+	AddedAt time.Time  `json:"added_at"`
+}
+
+// HistoryEntry is a closed trade in the long-term ledger
+type HistoryEntry struct {
+	ID             int64
+	TradeID        int64
+	TriggerType    string
+	PlayerName     string
+	ItemName       string
+	ItemAmount     float64
+	IsBulk         bool
+	League         string
+	CurrencyAmount float64
+	CurrencyType   string
+	Outcome        TradeStatus // completed, declined or expired
+	ReceivedAt     time.Time   // when the whisper came in
+	ClosedAt       time.Time
+	Duration       time.Duration // from whisper to close
+}
+
+// IsIncoming reports whether we were the seller.
+func (h HistoryEntry) IsIncoming() bool {
+	return h.TriggerType == "incoming_trade" || h.TriggerType == "incoming_bulk_trade"
+}
+
+// ItemDescription returns the item name together with the requested amount
+// of bulk trades.
+func (h HistoryEntry) ItemDescription() string {
+	return bulkItemName(h.ItemName, h.ItemAmount, h.IsBulk)
+}
+
+// bulkItemName puts the requested amount in front of the item name of bulk
+// trades.
+func bulkItemName(name string, amount float64, bulk bool) string {
+	if !bulk {
+		return name
+	}
+	return fmt.Sprintf("%g %s", amount, name)
+}
+
+// ItemDescription returns the item name together with the requested amount
+// of bulk trades and the gem level/quality of listings that carry them.
+func (t TradeEntry) ItemDescription() string {
+	item := bulkItemName(t.ItemName, t.ItemAmount, t.IsBulk)
+
+	switch {
+	case t.ItemLevel > 0 && t.ItemQuality > 0:
+		item = fmt.Sprintf("%s (level %d, %d%%)", item, t.ItemLevel, t.ItemQuality)
+	case t.ItemLevel > 0:
+		item = fmt.Sprintf("%s (level %d)", item, t.ItemLevel)
+	case t.ItemQuality > 0:
+		item = fmt.Sprintf("%s (%d%%)", item, t.ItemQuality)
+	}
+	return item
+}
+
+// NormalizeCurrency maps currency names as written in bulk whispers
+// ("Divine Orb") to the short form used by stash listings ("divine").
+func NormalizeCurrency(currencyType string) string {
+	currency := strings.ToLower(strings.TrimSpace(currencyType))
+	switch currency {
+	case "divine orb", "divine orbs":
+		return "divine"
+	case "exalted orb", "exalted orbs", "exalt", "exalts":
+		return "exalted"
+	case "chaos orb", "chaos orbs":
+		return "chaos"
+	}
+	return currency
+}
+
+// HasPosition reports whether the whisper named a stash tab and position.
+func (t TradeEntry) HasPosition() bool {
+	return t.StashTab != ""
+}
+
+// IsIncoming reports whether someone else wants to buy from us.
+func (t TradeEntry) IsIncoming() bool {
+	return t.TriggerType == "incoming_trade" || t.TriggerType == "incoming_bulk_trade"
+}
+
+// LogEventType identifies a game event recognized in Client.txt
+type LogEventType string
+
+const (
+	EventPlayerJoined LogEventType = "player_joined"
+	EventPlayerLeft   LogEventType = "player_left"
+
+	// Outcome of the trade window; the log line does not name the player
+	EventTradeAccepted  LogEventType = "trade_accepted"
+	EventTradeCancelled LogEventType = "trade_cancelled"
+
+	// Availability toggles, Enabled holds the new state
+	EventAFKChanged LogEventType = "afk_changed"
+	EventDNDChanged LogEventType = "dnd_changed"
+
+	// Zone changes: the area is generated first, then entered
+	EventAreaGenerated LogEventType = "area_generated"
+	EventZoneEntered   LogEventType = "zone_entered"
+)
+
+// LogEvent represents a Client.txt line that is not a trade whisper but
+// matters for ongoing trades
+type LogEvent struct {
+	Timestamp  time.Time
+	Type       LogEventType
+	PlayerName string
+	Enabled    bool   // AFK/DND events: mode switched on
+	Zone       string // zone entered, e.g. "Hideout" or "The Twilight Strand"
+	AreaID     string // internal id of a generated area, e.g. "MapWorldsStrand"
+	AreaLevel  int
+	Message    string
 }
 
 // Trigger represents a log trigger with its compiled regular expression
@@ -0,0 +1,94 @@
+package poe_log
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"hypr-exiled/internal/models"
+)
+
+// eventParsers recognize game events other than trade whispers. System
+// messages have an empty sender, hence the "] : " prefix, which keeps
+// player chat from spoofing them.
+var eventParsers = []struct {
+	eventType models.LogEventType
+	pattern   *regexp.Regexp
+	fill      func(event *models.LogEvent, matches []string)
+}{
+	{
+		eventType: models.EventPlayerJoined,
+		pattern:   regexp.MustCompile(`\] : (.+) has joined the area\.$`),
+		fill:      fillPlayer,
+	},
+	{
+		eventType: models.EventPlayerLeft,
+		pattern:   regexp.MustCompile(`\] : (.+) has left the area\.$`),
+		fill:      fillPlayer,
+	},
+	{
+		eventType: models.EventTradeAccepted,
+		pattern:   regexp.MustCompile(`\] : Trade accepted\.$`),
+		fill:      fillNothing,
+	},
+	{
+		eventType: models.EventTradeCancelled,
+		pattern:   regexp.MustCompile(`\] : Trade cancelled\.$`),
+		fill:      fillNothing,
+	},
+	{
+		eventType: models.EventAFKChanged,
+		pattern:   regexp.MustCompile(`\] : AFK mode is now (ON|OFF)`),
+		fill:      fillEnabled,
+	},
+	{
+		eventType: models.EventDNDChanged,
+		pattern:   regexp.MustCompile(`\] : (?:DND|Do Not Disturb) mode is now (ON|OFF)`),
+		fill:      fillEnabled,
+	},
+	{
+		eventType: models.EventAreaGenerated,
+		pattern:   regexp.MustCompile(`Generating level (\d+) area "([^"]+)"`),
+		fill: func(event *models.LogEvent, matches []string) {
+			event.AreaLevel, _ = strconv.Atoi(matches[1])
+			event.AreaID = matches[2]
+		},
+	},
+	{
+		eventType: models.EventZoneEntered,
+		pattern:   regexp.MustCompile(`\] : You have entered (.+?)\.?$`),
+		fill: func(event *models.LogEvent, matches []string) {
+			event.Zone = strings.TrimSpace(matches[1])
+		},
+	},
+}
+
+func fillPlayer(event *models.LogEvent, matches []string) {
+	event.PlayerName = strings.TrimSpace(matches[1])
+}
+
+func fillEnabled(event *models.LogEvent, matches []string) {
+	event.Enabled = matches[1] == "ON"
+}
+
+func fillNothing(*models.LogEvent, []string) {}
+
+// parseLogEvent returns the game event described by the line, if any.
+func parseLogEvent(timestamp time.Time, line string) (models.LogEvent, bool) {
+	for _, parser := range eventParsers {
+		matches := parser.pattern.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		event := models.LogEvent{
+			Timestamp: timestamp,
+			Type:      parser.eventType,
+			Message:   line,
+		}
+		parser.fill(&event, matches)
+		return event, true
+	}
+	return models.LogEvent{}, false
+}
@@ -0,0 +1,124 @@
+package poe_log
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"time"
+
+	"hypr-exiled/internal/models"
+	"hypr-exiled/pkg/global"
+)
+
+// ReplayOptions controls how a saved Client.txt is fed through the triggers.
+type ReplayOptions struct {
+	// Speed multiplies the pace of the original log: 1 replays in real time,
+	// 10 ten times faster. Zero or less replays without any delay.
+	Speed float64
+	// Since and Until limit the replay to lines within the time range.
+	// Zero values leave the range open.
+	Since time.Time
+	Until time.Time
+}
+
+// ReplayStats summarizes a finished replay.
+type ReplayStats struct {
+	Lines    int // lines read from the file
+	InRange  int // timestamped lines within Since/Until
+	Triggers int // trade entries produced by the triggers
+}
+
+// NewReplayWatcher creates a LogWatcher that is not bound to a game window,
+// so the session and window checks of the detector are skipped.
+func NewReplayWatcher(handler func(models.TradeEntry)) *LogWatcher {
+	return &LogWatcher{
+		handler:  handler,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Replay runs every line of a saved log file through processLogLine.
+func (w *LogWatcher) Replay(path string, opts ReplayOptions) (ReplayStats, error) {
+	log := global.GetLogger()
+	var stats ReplayStats
+
+	file, err := os.Open(path)
+	if err != nil {
+		return stats, fmt.Errorf("failed to open replay file: %w", err)
+	}
+	defer file.Close()
+
+	log.Info("Starting log replay",
+		"path", path,
+		"speed", opts.Speed,
+		"since", opts.Since,
+		"until", opts.Until)
+
+	// Count trade entries on their way to the real handler
+	handler := w.handler
+	w.handler = func(entry models.TradeEntry) {
+		stats.Triggers++
+		if handler != nil {
+			handler(entry)
+		}
+	}
+	defer func() { w.handler = handler }()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, maxScanTokenSize), maxScanTokenSize)
+
+	var previous time.Time
+	for scanner.Scan() {
+		select {
+		case <-w.stopChan:
+			log.Info("Replay stopped")
+			return stats, nil
+		default:
+		}
+
+		line := scanner.Text()
+		stats.Lines++
+
+		if !timestampRegex.MatchString(line) {
+			continue
+		}
+		timestamp, err := w.parseTimestamp(line)
+		if err != nil {
+			continue
+		}
+
+		if !opts.Since.IsZero() && timestamp.Before(opts.Since) {
+			continue
+		}
+		if !opts.Until.IsZero() && timestamp.After(opts.Until) {
+			break
+		}
+		stats.InRange++
+
+		if opts.Speed > 0 && !previous.IsZero() && timestamp.After(previous) {
+			delay := time.Duration(float64(timestamp.Sub(previous)) / opts.Speed)
+			select {
+			case <-w.stopChan:
+				log.Info("Replay stopped")
+				return stats, nil
+			case <-time.After(delay):
+			}
+		}
+		previous = timestamp
+
+		if err := w.processLogLine(line); err != nil {
+			log.Debug("Failed to process log line",
+				"error", err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return stats, fmt.Errorf("failed to read replay file: %w", err)
+	}
+
+	log.Info("Log replay finished",
+		"lines", stats.Lines,
+		"in_range", stats.InRange,
+		"triggers", stats.Triggers)
+	return stats, nil
+}
@@ -0,0 +1,144 @@
+package poe_log
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"hypr-exiled/pkg/global"
+)
+
+// Increase scanner buffer size to handle long lines
+const maxScanTokenSize = 1024 * 1024 // 1MB
+
+// tailEvent describes what happened to the watched log file.
+type tailEvent int
+
+const (
+	tailWrite   tailEvent = iota // file content changed (append or truncation)
+	tailMoved                    // file was renamed or deleted
+	tailCreated                  // a file appeared under the watched name
+)
+
+func (e tailEvent) String() string {
+	switch e {
+	case tailWrite:
+		return "write"
+	case tailMoved:
+		return "moved"
+	case tailCreated:
+		return "created"
+	default:
+		return fmt.Sprintf("event(%d)", int(e))
+	}
+}
+
+// tailNotifier delivers file change events for an event-driven tail.
+type tailNotifier interface {
+	Events() <-chan tailEvent
+	Close() error
+}
+
+// logTail keeps track of how far an open log file has been consumed.
+type logTail struct {
+	file     *os.File
+	path     string
+	info     os.FileInfo // identity (device and inode) of the open file
+	missing  bool        // path no longer exists on disk
+	offset   int64
+	lastSize int64
+	buf      []byte
+}
+
+func newLogTail(file *os.File) (*logTail, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	// Start at the end: only lines written after startup are of interest
+	return &logTail{
+		file:     file,
+		path:     file.Name(),
+		info:     stat,
+		offset:   stat.Size(),
+		lastSize: stat.Size(),
+		buf:      make([]byte, maxScanTokenSize),
+	}, nil
+}
+
+// reopen switches to a freshly opened file, which is read from the start
+// since everything in it was written after the previous file was replaced.
+func (t *logTail) reopen(file *os.File) error {
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	t.file.Close()
+	t.file = file
+	t.path = file.Name()
+	t.info = stat
+	t.missing = false
+	t.offset = 0
+	t.lastSize = 0
+	return nil
+}
+
+// readNew reads every line appended since the last call and passes it to process.
+func (t *logTail) readNew(process func(line string)) error {
+	log := global.GetLogger()
+
+	stat, err := t.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	currentSize := stat.Size()
+
+	// Handle file truncation
+	if currentSize < t.lastSize {
+		log.Info("File was truncated, resetting",
+			"old_size", t.lastSize,
+			"new_size", currentSize)
+		t.offset = 0
+		t.lastSize = 0
+	}
+
+	if currentSize <= t.offset {
+		return nil
+	}
+
+	// Seek to where we left off
+	if _, err := t.file.Seek(t.offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek file: %w", err)
+	}
+
+	// Only consume up to the size we just observed, so lines appended while
+	// reading are picked up by the next call instead of being processed twice.
+	scanner := bufio.NewScanner(io.LimitReader(t.file, currentSize-t.offset))
+	scanner.Buffer(t.buf, maxScanTokenSize)
+
+	// Only hand out newline-terminated lines. A partly flushed last line is
+	// left unread and picked up whole once the rest of it has been written.
+	var consumed int64
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, false)
+		consumed += int64(advance)
+		return advance, token, err
+	})
+
+	for scanner.Scan() {
+		process(scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scanner error: %w", err)
+	}
+
+	// Update our offset up to the last complete line
+	t.offset += consumed
+	t.lastSize = currentSize
+	return nil
+}
@@ -0,0 +1,123 @@
+//go:build linux
+
+package poe_log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"hypr-exiled/pkg/global"
+)
+
+const (
+	inotifyFileMask = syscall.IN_MODIFY | syscall.IN_ATTRIB |
+		syscall.IN_MOVE_SELF | syscall.IN_DELETE_SELF
+	inotifyDirMask = syscall.IN_CREATE | syscall.IN_MOVED_TO
+)
+
+// inotifyTail reports changes of a single file using the kernel's inotify API.
+// The parent directory is watched as well, so that a file re-created under
+// the same name is noticed right away.
+type inotifyTail struct {
+	// The inotify descriptor is wrapped in an *os.File so that a blocked
+	// Read is interrupted when the notifier is closed.
+	fd     *os.File
+	dirWd  int32
+	name   string
+	events chan tailEvent
+}
+
+func newInotifyTail(path string) (tailNotifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify init failed: %w", err)
+	}
+
+	if _, err := syscall.InotifyAddWatch(fd, path, inotifyFileMask); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to watch %s: %w", path, err)
+	}
+
+	dirWd, err := syscall.InotifyAddWatch(fd, filepath.Dir(path), inotifyDirMask)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to watch directory of %s: %w", path, err)
+	}
+
+	t := &inotifyTail{
+		fd:     os.NewFile(uintptr(fd), "inotify"),
+		dirWd:  int32(dirWd),
+		name:   filepath.Base(path),
+		events: make(chan tailEvent, 16),
+	}
+	go t.readLoop()
+	return t, nil
+}
+
+func (t *inotifyTail) Events() <-chan tailEvent {
+	return t.events
+}
+
+func (t *inotifyTail) Close() error {
+	return t.fd.Close()
+}
+
+func (t *inotifyTail) readLoop() {
+	log := global.GetLogger()
+	defer close(t.events)
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := t.fd.Read(buf)
+		if err != nil {
+			// Closing the notifier ends up here as well
+			log.Debug("inotify read loop finished", "reason", err)
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			offset = nameStart + int(event.Len)
+
+			if event.Wd == t.dirWd {
+				name := strings.TrimRight(string(buf[nameStart:offset]), "\x00")
+				if name == t.name {
+					t.send(tailCreated)
+				}
+				continue
+			}
+
+			switch {
+			case event.Mask&syscall.IN_Q_OVERFLOW != 0:
+				// Events were dropped, a read catches up with whatever happened
+				t.send(tailWrite)
+			case event.Mask&(syscall.IN_MOVE_SELF|syscall.IN_DELETE_SELF) != 0:
+				t.send(tailMoved)
+			case event.Mask&(syscall.IN_MODIFY|syscall.IN_ATTRIB) != 0:
+				t.send(tailWrite)
+			}
+		}
+	}
+}
+
+// send delivers an event without blocking. Dropping one while the queue is
+// full is harmless: the pending events already cause a full catch-up read.
+func (t *inotifyTail) send(e tailEvent) {
+	select {
+	case t.events <- e:
+	default:
+	}
+}
+
+// fileIdentity formats the device and inode of a file for logging.
+func fileIdentity(info os.FileInfo) string {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return fmt.Sprintf("%d:%d", st.Dev, st.Ino)
+	}
+	return ""
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package poe_log
+
+import (
+	"fmt"
+	"os"
+)
+
+func newInotifyTail(path string) (tailNotifier, error) {
+	return nil, fmt.Errorf("event-driven tailing is only supported on linux")
+}
+
+func fileIdentity(info os.FileInfo) string {
+	return ""
+}
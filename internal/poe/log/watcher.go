@@ -1,7 +1,6 @@
 package poe_log
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"regexp"
@@ -13,7 +12,9 @@ import (
 	"hypr-exiled/internal/models"
 	"hypr-exiled/internal/poe/window"
 
+	"hypr-exiled/pkg/config"
 	"hypr-exiled/pkg/global"
+	"hypr-exiled/pkg/notify"
 )
 
 // Only match lines that start with a valid timestamp
@@ -21,6 +22,7 @@ var timestampRegex = regexp.MustCompile(`^\d{4}/\d{2}/\d{2}\s+\d{2}:\d{2}:\d{2}`
 
 type LogWatcher struct {
 	handler      func(models.TradeEntry)
+	eventHandler func(models.LogEvent)
 	windowCheck  *window.Detector
 	stopChan     chan struct{}
 	mu           sync.Mutex
@@ -44,6 +46,14 @@ func NewLogWatcher(handler func(models.TradeEntry), detector *window.Detector) (
 	return watcher, nil
 }
 
+// SetEventHandler registers a callback for game events that are not trade
+// whispers, such as players joining or leaving the area.
+func (w *LogWatcher) SetEventHandler(handler func(models.LogEvent)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.eventHandler = handler
+}
+
 func (w *LogWatcher) SetPathOverride(p string) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
@@ -70,7 +80,6 @@ func (w *LogWatcher) Watch() error {
 		log.Error("Failed to open log file", err)
 		return fmt.Errorf("failed to open log file: %w", err)
 	}
-	defer file.Close()
 
 	// Create done channel for cleanup signaling
 	done := make(chan struct{})
@@ -92,85 +101,164 @@ func (w *LogWatcher) Watch() error {
 	}
 }
 
+// pollInterval is how often the polling fallback checks Client.txt for growth.
+const pollInterval = 500 * time.Millisecond
+
+// inotifySafetyInterval bounds the delay should the filesystem silently drop
+// change events (e.g. some FUSE or network mounts).
+const inotifySafetyInterval = 5 * time.Second
+
 func (w *LogWatcher) watchLoop(file *os.File) error {
-	log := global.GetLogger()
+	cfg, log, _ := global.GetAll()
 
-	// Get initial file size
-	stat, _ := file.Stat()
-	initialSize := stat.Size()
-	log.Info("Initial file size", "size", initialSize)
+	tail, err := newLogTail(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	defer func() { tail.file.Close() }()
+	log.Info("Initial file size", "size", tail.offset, "file_id", fileIdentity(tail.info))
 
-	// Instead of seeking to end immediately, we'll keep track of where we need to read from
-	var offset = initialSize
-	lastSize := initialSize
+	mode := cfg.GetLogWatchMode()
+	if mode == "poll" {
+		log.Info("Tailing log file by polling", "interval", pollInterval)
+		return w.pollLoop(tail)
+	}
 
-	// Increase scanner buffer size to handle long lines
-	const maxScanTokenSize = 1024 * 1024 // 1MB
-	buf := make([]byte, maxScanTokenSize)
+	notifier, err := newInotifyTail(tail.path)
+	if err != nil {
+		if mode == "inotify" {
+			return fmt.Errorf("failed to start inotify tail: %w", err)
+		}
+		log.Warn("Event-driven tailing unavailable, falling back to polling",
+			"error", err,
+			"interval", pollInterval)
+		return w.pollLoop(tail)
+	}
+
+	log.Info("Tailing log file using inotify")
+	return w.eventLoop(tail, notifier)
+}
+
+// eventLoop reads new lines whenever the notifier reports a change.
+func (w *LogWatcher) eventLoop(tail *logTail, notifier tailNotifier) error {
+	log := global.GetLogger()
+	defer func() { notifier.Close() }()
+
+	safety := time.NewTicker(inotifySafetyInterval)
+	defer safety.Stop()
 
 	for {
 		select {
 		case <-w.stopChan:
 			return nil
-		default:
-			// Check current file size
-			stat, err := file.Stat()
-			if err != nil {
-				log.Error("Failed to stat file", err)
-				time.Sleep(500 * time.Millisecond)
-				continue
+		case event, ok := <-notifier.Events():
+			if !ok {
+				log.Warn("inotify stream closed, falling back to polling")
+				return w.pollLoop(tail)
 			}
+			log.Debug("Log file event", "event", event)
+		case <-safety.C:
+		}
 
-			currentSize := stat.Size()
+		if err := tail.readNew(w.handleLine); err != nil {
+			log.Error("Failed to read log file", err)
+		}
 
-			// Handle file truncation
-			if currentSize < lastSize {
-				log.Info("File was truncated, resetting",
-					"old_size", lastSize,
-					"new_size", currentSize)
-				offset = 0
-				lastSize = 0
-			}
+		if !w.followRotation(tail) {
+			continue
+		}
+
+		// The old watch still points at the replaced file
+		notifier.Close()
+		next, err := newInotifyTail(tail.path)
+		if err != nil {
+			log.Warn("Failed to watch re-opened log file, falling back to polling", "error", err)
+			return w.pollLoop(tail)
+		}
+		notifier = next
+
+		// Catch up with anything written before the new watch was in place
+		if err := tail.readNew(w.handleLine); err != nil {
+			log.Error("Failed to read log file", err)
+		}
+	}
+}
+
+// pollLoop checks the file for new content at a fixed interval.
+func (w *LogWatcher) pollLoop(tail *logTail) error {
+	log := global.GetLogger()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
 
-			// If there's new content
-			if currentSize > offset {
-				// Seek to where we left off
-				if _, err := file.Seek(offset, 0); err != nil {
-					log.Error("Failed to seek file", err)
-					time.Sleep(500 * time.Millisecond)
-					continue
-				}
-
-				// Create new scanner for this read
-				scanner := bufio.NewScanner(file)
-				scanner.Buffer(buf, maxScanTokenSize)
-
-				// Read all new lines
-				for scanner.Scan() {
-					line := scanner.Text()
-					log.Debug("Read new line",
-						"content", line[:min(len(line), 100)],
-						"length", len(line))
-
-					if err := w.processLogLine(line); err != nil {
-						log.Debug("Failed to process log line",
-							"error", err)
-					}
-				}
-
-				if err := scanner.Err(); err != nil {
-					log.Error("Scanner error", err)
-					time.Sleep(500 * time.Millisecond)
-					continue
-				}
-
-				// Update our offset
-				offset = currentSize
-				lastSize = currentSize
+	for {
+		select {
+		case <-w.stopChan:
+			return nil
+		case <-ticker.C:
+			if err := tail.readNew(w.handleLine); err != nil {
+				log.Error("Failed to read log file", err)
 			}
+			w.followRotation(tail)
+		}
+	}
+}
+
+// followRotation compares the file behind the log path with the one being
+// read (by device and inode) and re-opens the path if Client.txt was moved,
+// deleted and re-created, or replaced. It reports whether the file changed.
+func (w *LogWatcher) followRotation(tail *logTail) bool {
+	log := global.GetLogger()
+	notifier := global.GetNotifier()
 
-			time.Sleep(500 * time.Millisecond)
+	path := w.getActivePath()
+	info, err := os.Stat(path)
+	if err != nil {
+		if !tail.missing {
+			log.Warn("Log file is gone, waiting for it to be re-created",
+				"path", path,
+				"error", err)
+			tail.missing = true
 		}
+		return false
+	}
+
+	if path == tail.path && os.SameFile(info, tail.info) {
+		tail.missing = false
+		return false
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Error("Failed to re-open log file", err, "path", path)
+		return false
+	}
+
+	oldID := fileIdentity(tail.info)
+	if err := tail.reopen(file); err != nil {
+		log.Error("Failed to switch to re-opened log file", err, "path", path)
+		file.Close()
+		return false
+	}
+
+	log.Info("Log file was replaced, re-opened it",
+		"path", path,
+		"old_file_id", oldID,
+		"new_file_id", fileIdentity(tail.info))
+	notifier.Show("Client.txt was replaced, resumed watching the new file", notify.Info)
+	return true
+}
+
+func (w *LogWatcher) handleLine(line string) {
+	log := global.GetLogger()
+	log.Debug("Read new line",
+		"content", line[:min(len(line), 100)],
+		"length", len(line))
+
+	if err := w.processLogLine(line); err != nil {
+		log.Debug("Failed to process log line",
+			"error", err)
 	}
 }
 
@@ -193,66 +281,106 @@ func (w *LogWatcher) processLogLine(line string) error {
 		return nil
 	}
 
-	// Check if log line should be processed based on window and session state
-	if !w.windowCheck.CheckLogLineValidity(timestamp, line) {
+	// Check if log line should be processed based on window and session state.
+	// Replays have no detector and feed every line to the triggers.
+	if w.windowCheck != nil && !w.windowCheck.CheckLogLineValidity(timestamp, line) {
+		return nil
+	}
+
+	// Game events never match a trade trigger
+	if event, ok := parseLogEvent(timestamp, line); ok {
+		log.Info("Log event",
+			"type", event.Type,
+			"player", event.PlayerName)
+
+		w.mu.Lock()
+		eventHandler := w.eventHandler
+		w.mu.Unlock()
+		if eventHandler != nil {
+			eventHandler(event)
+		}
 		return nil
 	}
 
 	// Process trade messages
 	for triggerName, trigger := range cfg.GetCompiledTriggers() {
-		matches := trigger.FindStringSubmatch(line)
-		if len(matches) > 1 {
-			// Convert currency amount to float
-			amount, _ := strconv.ParseFloat(matches[3], 64)
-
-			// Parse position coordinates
-			left, _ := strconv.Atoi(matches[7])
-			top, _ := strconv.Atoi(matches[8])
-
-			// Trim any whitespace from the league name
-			league := strings.TrimSpace(matches[5])
-
-			// Create the trade entry
-			entry := models.TradeEntry{
-				Timestamp:      timestamp,
-				TriggerType:    triggerName,
-				PlayerName:     matches[1],
-				ItemName:       matches[2],
-				CurrencyAmount: amount,
-				CurrencyType:   matches[4],
-				League:         league, // Add league field to your struct if not present
-				StashTab:       matches[6],
-				Position: struct {
-					Left int
-					Top  int
-				}{
-					Left: left,
-					Top:  top,
-				},
-				Message:      line,
-				IsBuyRequest: triggerName == "outgoing_trade",
-			}
+		fields, ok := trigger.Match(line)
+		if !ok {
+			continue
+		}
 
-			log.Info("Triggered trade event",
-				"trigger", triggerName,
-				"player", entry.PlayerName,
-				"item", entry.ItemName,
-				"amount", entry.CurrencyAmount,
-				"currency", entry.CurrencyType,
-				"league", entry.League,
-				"stash", entry.StashTab,
-				"position", fmt.Sprintf("left: %d, top: %d", entry.Position.Left, entry.Position.Top),
-			)
-
-			// Call the trade entry callback if provided
-			if w.handler != nil {
-				w.handler(entry)
-			}
+		entry := newTradeEntry(triggerName, timestamp, line, fields)
+
+		log.Info("Triggered trade event",
+			"trigger", triggerName,
+			"player", entry.PlayerName,
+			"item", entry.ItemName,
+			"item_amount", entry.ItemAmount,
+			"amount", entry.CurrencyAmount,
+			"currency", entry.CurrencyType,
+			"league", entry.League,
+			"stash", entry.StashTab,
+			"position", fmt.Sprintf("left: %d, top: %d", entry.Position.Left, entry.Position.Top),
+			"note", entry.Note,
+		)
+
+		// Call the trade entry callback if provided
+		if w.handler != nil {
+			w.handler(entry)
 		}
 	}
 	return nil
 }
 
+// newTradeEntry fills a trade entry from the named fields a trigger captured.
+// Fields the trigger does not provide keep their zero value.
+func newTradeEntry(triggerName string, timestamp time.Time, line string, fields map[string]string) models.TradeEntry {
+	// Convert currency amount to float
+	amount, _ := strconv.ParseFloat(fields[config.FieldAmount], 64)
+	itemAmount, _ := strconv.ParseFloat(fields[config.FieldItemAmount], 64)
+	isBulk := fields[config.FieldItemAmount] != ""
+
+	// Parse position coordinates
+	left, _ := strconv.Atoi(fields[config.FieldLeft])
+	top, _ := strconv.Atoi(fields[config.FieldTop])
+
+	// Gem qualifiers
+	level, _ := strconv.Atoi(fields[config.FieldLevel])
+	quality, _ := strconv.Atoi(fields[config.FieldQuality])
+
+	entry := models.TradeEntry{
+		Timestamp:      timestamp,
+		TriggerType:    triggerName,
+		PlayerName:     playerName(fields[config.FieldPlayer]),
+		ItemName:       strings.TrimSpace(fields[config.FieldItem]),
+		CurrencyAmount: amount,
+		CurrencyType:   strings.TrimSpace(fields[config.FieldCurrency]),
+		League:         strings.TrimSpace(fields[config.FieldLeague]),
+		StashTab:       fields[config.FieldStash],
+		Message:        line,
+		IsBuyRequest:   triggerName == "outgoing_trade" || triggerName == "outgoing_bulk_trade",
+		IsBulk:         isBulk,
+		ItemAmount:     itemAmount,
+		ItemLevel:      level,
+		ItemQuality:    quality,
+		Note:           strings.TrimSpace(fields[config.FieldNote]),
+	}
+	entry.Position.Left = left
+	entry.Position.Top = top
+
+	return entry
+}
+
+// guildTagRegex matches the guild tag the game puts in front of whisper
+// senders, e.g. "<GUILD> PlayerName".
+var guildTagRegex = regexp.MustCompile(`^<[^>]*>\s*`)
+
+// playerName strips the guild tag, so the name matches area join/leave lines
+// and works in /invite and /tradewith.
+func playerName(raw string) string {
+	return guildTagRegex.ReplaceAllString(strings.TrimSpace(raw), "")
+}
+
 func (w *LogWatcher) parseTimestamp(line string) (time.Time, error) {
 	parts := strings.SplitN(line, " ", 4)
 	if len(parts) < 4 {
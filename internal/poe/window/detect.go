@@ -12,14 +12,25 @@ import (
 	"hypr-exiled/pkg/notify"
 )
 
+const (
+	// pollInterval is how often the window is looked up without window events
+	pollInterval = 2 * time.Second
+	// eventPollInterval is the safety poll when window events are followed
+	eventPollInterval = 30 * time.Second
+	// eventRetryDelay is the wait before reconnecting a broken event stream
+	eventRetryDelay = 5 * time.Second
+)
+
 // Detector handles POE window detection
 type Detector struct {
 	hyprExiledSessionStart time.Time
 	lastResetTimestamp     time.Time
 	windowFoundTime        time.Time
 	isWindowActive         bool
+	isWindowFocused        bool // only tracked with window events
 	currentWindow          wm.Window
 	mu                     sync.RWMutex
+	detectMu               sync.Mutex // polls and window events detect one at a time
 	windowClasses          []string
 	wmManager              *wm.Manager
 	stopChan               chan struct{}
@@ -70,6 +81,9 @@ func (d *Detector) Detect() error {
 	notifier := global.GetNotifier()
 	cfg := global.GetConfig()
 
+	d.detectMu.Lock()
+	defer d.detectMu.Unlock()
+
 	window, err := d.wmManager.FindWindow(d.windowClasses)
 	if err != nil {
 		log.Error("Error detecting game window", err)
@@ -90,6 +104,7 @@ func (d *Detector) Detect() error {
 		} else {
 			log.Info("PoE window lost")
 			notifier.Show("PoE window lost", notify.Info)
+			d.isWindowFocused = false
 		}
 		d.isWindowActive = isActive
 	}
@@ -168,15 +183,38 @@ func (d *Detector) CheckLogLineValidity(lineTimestamp time.Time, line string) bo
 		return false
 	}
 
-	// Only process lines with "@From" or "@To"
-	if !strings.Contains(line, "@From") && !strings.Contains(line, "@To") {
-		log.Debug("Rejecting line - not a trade message")
+	// Only process trade whispers and the game events the log watcher understands
+	if !isRelevantLine(line) {
+		log.Debug("Rejecting line - not a trade message or known event")
 		return false
 	}
 
 	return true
 }
 
+// relevantLineMarkers are substrings of the lines worth parsing.
+var relevantLineMarkers = []string{
+	"@From",
+	"@To",
+	"has joined the area.",
+	"has left the area.",
+	"Trade accepted.",
+	"Trade cancelled.",
+	"mode is now ON",
+	"mode is now OFF",
+	"You have entered",
+	"Generating level",
+}
+
+func isRelevantLine(line string) bool {
+	for _, marker := range relevantLineMarkers {
+		if strings.Contains(line, marker) {
+			return true
+		}
+	}
+	return false
+}
+
 // Start begins monitoring window state
 func (d *Detector) Start() error {
 	log := global.GetLogger()
@@ -190,15 +228,23 @@ func (d *Detector) Start() error {
 		d.stopChan = make(chan struct{})
 		d.stopped = false
 	}
+	stopChan := d.stopChan
 	d.mu.Unlock()
 
+	// Window managers with an event stream only need a slow safety poll
+	interval := pollInterval
+	if source, ok := d.wmManager.Events(); ok {
+		interval = eventPollInterval
+		go d.watchEvents(source, stopChan)
+	}
+
 	go func() {
-		ticker := time.NewTicker(2 * time.Second)
+		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 
 		for {
 			select {
-			case <-d.stopChan:
+			case <-stopChan:
 				log.Info("Window detector stopped")
 				return
 			case <-ticker.C:
@@ -209,10 +255,75 @@ func (d *Detector) Start() error {
 		}
 	}()
 
-	log.Info("Window detector started")
+	log.Info("Window detector started", "poll_interval", interval)
 	return nil
 }
 
+// watchEvents follows the window events of the window manager until the
+// detector stops, reconnecting when the stream breaks.
+func (d *Detector) watchEvents(source wm.EventSource, stopChan chan struct{}) {
+	log := global.GetLogger()
+
+	for {
+		err := source.WatchEvents(stopChan, d.handleWindowEvent)
+
+		select {
+		case <-stopChan:
+			return
+		default:
+		}
+
+		log.Error("Window event stream failed, retrying", err, "retry_in", eventRetryDelay)
+		select {
+		case <-stopChan:
+			return
+		case <-time.After(eventRetryDelay):
+		}
+	}
+}
+
+// handleWindowEvent looks the PoE window up again when a window of its class
+// opens or the current one closes, and tracks whether it has focus.
+func (d *Detector) handleWindowEvent(event wm.WindowEvent) {
+	log := global.GetLogger()
+
+	switch event.Type {
+	case wm.WindowOpened:
+		if !d.matchesClass(event.Class) {
+			return
+		}
+	case wm.WindowClosed:
+		if event.Address != d.GetCurrentWindow().Address {
+			return
+		}
+	case wm.WindowFocused:
+		d.mu.Lock()
+		focused := d.isWindowActive && event.Address == d.currentWindow.Address
+		changed := focused != d.isWindowFocused
+		d.isWindowFocused = focused
+		d.mu.Unlock()
+
+		if changed {
+			log.Debug("PoE window focus changed", "focused", focused)
+		}
+		return
+	}
+
+	log.Debug("Window event, detecting PoE window", "event", event.Type, "address", event.Address)
+	if err := d.Detect(); err != nil {
+		log.Error("Window detection error", err)
+	}
+}
+
+func (d *Detector) matchesClass(class string) bool {
+	for _, candidate := range d.windowClasses {
+		if strings.Contains(strings.ToLower(class), strings.ToLower(candidate)) {
+			return true
+		}
+	}
+	return false
+}
+
 func (d *Detector) GetCurrentWindow() wm.Window {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
@@ -231,6 +342,14 @@ func (d *Detector) IsActive() bool {
 	return d.isWindowActive
 }
 
+// IsFocused reports whether the PoE window has focus. It is only known for
+// window managers with window events and false otherwise.
+func (d *Detector) IsFocused() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.isWindowFocused
+}
+
 // Stop stops the window detection loop
 func (d *Detector) Stop() error {
 	log := global.GetLogger()
@@ -2,13 +2,15 @@ package rofi
 
 import (
 	"fmt"
+	"html"
 	"hypr-exiled/internal/models"
 	"hypr-exiled/pkg/global"
 	"hypr-exiled/pkg/logger"
 	"os/exec"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds the configuration for the Rofi menu.
@@ -17,18 +19,22 @@ type Config struct {
 	Message string
 }
 
-// ActionHandler defines a function type for handling custom actions.
-type ActionHandler func(selected string) error
+// ActionHandler defines a function type for handling custom actions on the
+// trade with the given database id.
+type ActionHandler func(tradeID int64) error
 
 var (
 	baseArgs = []string{
 		"-dmenu",
 		"-markup-rows",
+		"-format", "i", // print the index of the selected row
 		"-kb-custom-1", "t",
 		"-show-icons",
 		"-kb-custom-2", "p",
 		"-kb-custom-3", "f",
 		"-kb-custom-4", "d",
+		"-kb-custom-5", "b",
+		"-kb-custom-6", "s",
 		"-kb-accept-entry", "Return",
 		"-markup",
 		"-eh", "2",
@@ -36,7 +42,7 @@ var (
 
 	TradeConfig = Config{
 		Args:    []string{},
-		Message: "P (party) | T (trade) | F (finish) | D (delete)",
+		Message: "P (party) | T (trade) | F (finish) | D (delete) | B (block) | S (sort)",
 	}
 )
 
@@ -47,11 +53,14 @@ type TradeDisplayManager struct {
 	partyHandler  ActionHandler
 	finishHandler ActionHandler
 	deleteHandler ActionHandler
+	blockHandler  ActionHandler
+	sortHandler   func() error
 	log           *logger.Logger
 }
 
-// NewDisplayManager creates a new DisplayManager instance.
-func NewTradeDisplayManager(tradeHandler, partyHandler, finishHandler, deleteHandler ActionHandler) *TradeDisplayManager {
+// NewDisplayManager creates a new DisplayManager instance. The sort handler
+// switches the order of the trades and shows them again.
+func NewTradeDisplayManager(tradeHandler, partyHandler, finishHandler, deleteHandler, blockHandler ActionHandler, sortHandler func() error) *TradeDisplayManager {
 	log := global.GetLogger()
 	log.Info("Initializing Rofi Trade DisplayManager")
 
@@ -72,6 +81,8 @@ func NewTradeDisplayManager(tradeHandler, partyHandler, finishHandler, deleteHan
 		partyHandler:  partyHandler,
 		finishHandler: finishHandler,
 		deleteHandler: deleteHandler,
+		blockHandler:  blockHandler,
+		sortHandler:   sortHandler,
 		log:           log,
 	}
 }
@@ -85,32 +96,54 @@ func (d *TradeDisplayManager) FormatTrade(trade models.TradeEntry, index int) st
 		"exalted": fmt.Sprintf("\x00icon\x1f%s", filepath.Join(config.GetAssetsDir(), "exalt.png")),
 		"chaos":   fmt.Sprintf("\x00icon\x1f%s", filepath.Join(config.GetAssetsDir(), "chaos.png")),
 	}
+	currencyNames := map[string]string{
+		"divine":  "Divs",
+		"exalted": "Exs",
+		"chaos":   "Chs",
+	}
 
 	currencyStr := fmt.Sprintf("%.0f", trade.CurrencyAmount)
 	if trade.CurrencyAmount != float64(int(trade.CurrencyAmount)) {
 		currencyStr = fmt.Sprintf("%.2f", trade.CurrencyAmount)
 	}
 
-	currencyName := "Divs"
-	if trade.CurrencyType == "exalted" {
-		currencyName = "Exs"
-	}
-	if trade.CurrencyType == "chaos" {
-		currencyName = "Chs"
+	currency := models.NormalizeCurrency(trade.CurrencyType)
+	currencyName, exists := currencyNames[currency]
+	if !exists {
+		currencyName = trade.CurrencyType
 	}
 
-	symbol, exists := currencySymbols[trade.CurrencyType]
+	symbol, exists := currencySymbols[currency]
 	if !exists {
 		symbol = trade.CurrencyType
 	}
 
-	formattedTrade := fmt.Sprintf("[%d] %s %s > %s&#x0a;@%s %s",
-		index, // Add an index to uniquely identify the trade
+	summary := fmt.Sprintf("[%d] %s %s > %s",
+		index, // Same row number rofi prints with -format i
 		currencyStr,
 		currencyName,
-		trade.ItemName,
-		trade.PlayerName,
-		symbol)
+		trade.ItemDescription())
+
+	// State, waiting time and the buyer's note go on the second line; the
+	// icon must stay last
+	buyer := fmt.Sprintf("@%s · %s · %s", trade.PlayerName, statusLabel(trade.Status), waitingTime(trade))
+	if trade.Pings > 1 {
+		summary += fmt.Sprintf(" x%d", trade.Pings)
+	}
+	if trade.PlayerList == models.PlayerAllowed {
+		summary = "★ " + summary
+	}
+	if trade.BuyerArrived {
+		summary = "<b>" + summary + "</b>"
+	}
+	if trade.WhileMapping {
+		buyer += " · while mapping"
+	}
+	if trade.Note != "" {
+		buyer += fmt.Sprintf(" <i>“%s”</i>", html.EscapeString(trade.Note))
+	}
+
+	formattedTrade := fmt.Sprintf("%s&#x0a;%s %s", summary, buyer, symbol)
 
 	log.Debug("Formatted trade with index",
 		"index", index,
@@ -120,40 +153,64 @@ func (d *TradeDisplayManager) FormatTrade(trade models.TradeEntry, index int) st
 	return formattedTrade
 }
 
-func (d *TradeDisplayManager) ExtractPlayerName(selected string) (string, error) {
-	log := global.GetLogger()
-
-	re := regexp.MustCompile(`\[\d+\] .*&#x0a;@([^\s]+)`)
-	matches := re.FindStringSubmatch(selected)
+var statusLabels = map[models.TradeStatus]string{
+	models.TradeStatusNew:       "new",
+	models.TradeStatusInvited:   "invited",
+	models.TradeStatusInHideout: "<b>in hideout</b>",
+	models.TradeStatusTrading:   "trading",
+	models.TradeStatusCompleted: "completed",
+	models.TradeStatusDeclined:  "declined",
+	models.TradeStatusExpired:   "expired",
+}
 
-	if len(matches) < 2 {
-		log.Error("Failed to extract player name",
-			fmt.Errorf("invalid selected string format: %s", selected))
-		return "", fmt.Errorf("invalid selected string format: %s", selected)
+func statusLabel(status models.TradeStatus) string {
+	if label, ok := statusLabels[status]; ok {
+		return label
 	}
+	return string(models.TradeStatusNew)
+}
 
-	playerName := matches[1]
-
-	log.Debug("Extracted trade details",
-		"player_name", playerName,
-		"selected_string", selected)
+// waitingTime formats how long ago the trade came in, e.g. "7m" or "1h05m".
+func waitingTime(trade models.TradeEntry) string {
+	if trade.CreatedAt.IsZero() {
+		return "now"
+	}
 
-	return playerName, nil
+	waiting := time.Since(trade.CreatedAt)
+	switch {
+	case waiting < time.Minute:
+		return "<1m"
+	case waiting < time.Hour:
+		return fmt.Sprintf("%dm", int(waiting.Minutes()))
+	default:
+		return fmt.Sprintf("%dh%02dm", int(waiting.Hours()), int(waiting.Minutes())%60)
+	}
 }
 
-// DisplayTrades displays the trades in a Rofi menu.
-func (d *TradeDisplayManager) DisplayTrades(trades []string) error {
+// DisplayTrades displays the trades in a Rofi menu. The header, if any, is
+// shown above the key bindings in the message bar.
+func (d *TradeDisplayManager) DisplayTrades(trades []models.TradeEntry, header string) error {
 	d.log.Debug("Starting DisplayTrades", "trade_count", len(trades))
 	if len(trades) == 0 {
 		d.log.Warn("No trades to display")
 		return fmt.Errorf("no trades to display")
 	}
 
-	args := append(d.config.Args, "-mesg", d.config.Message)
+	options := make([]string, len(trades))
+	for i, trade := range trades {
+		options[i] = d.FormatTrade(trade, i)
+	}
+
+	message := d.config.Message
+	if header != "" {
+		message = html.EscapeString(header) + "\n" + message
+	}
+
+	args := append(d.config.Args, "-mesg", message)
 	d.log.Debug("Constructed Rofi command", "args", args)
 
 	cmd := exec.Command("rofi", args...)
-	cmd.Stdin = strings.NewReader(strings.Join(trades, "\n"))
+	cmd.Stdin = strings.NewReader(strings.Join(options, "\n"))
 	d.log.Info("Executing Rofi command", "command", cmd.String())
 
 	// ⬇️ nur STDOUT (keine Fontconfig-/Pango-Warnungen in der Auswahl)
@@ -161,7 +218,7 @@ func (d *TradeDisplayManager) DisplayTrades(trades []string) error {
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			d.log.Debug("Rofi exited with code", "exit_code", exitErr.ExitCode())
-			return d.handleExitCode(string(output), exitErr.ExitCode())
+			return d.handleExitCode(string(output), exitErr.ExitCode(), trades)
 		}
 		d.log.Error("Failed to run Rofi", err)
 		return fmt.Errorf("failed to run rofi: %w", err)
@@ -170,34 +227,57 @@ func (d *TradeDisplayManager) DisplayTrades(trades []string) error {
 	return nil
 }
 
-// handleExitCode processes the Rofi exit code and executes the corresponding handler.
-func (d *TradeDisplayManager) handleExitCode(selected string, exitCode int) error {
+// handleExitCode processes the Rofi exit code and executes the corresponding
+// handler for the selected trade. Rofi prints the index of the selected row.
+func (d *TradeDisplayManager) handleExitCode(selected string, exitCode int, trades []models.TradeEntry) error {
+	// S does not act on a trade
+	if exitCode == 15 && d.sortHandler != nil {
+		d.log.Info("Sort action triggered")
+		return d.sortHandler()
+	}
+
 	selected = strings.TrimSpace(selected)
 	if selected == "" {
 		d.log.Debug("No selection made in Rofi")
 		return nil
 	}
-	d.log.Debug("Processing Rofi exit code", "exit_code", exitCode, "selected", selected)
+
+	index, err := strconv.Atoi(selected)
+	if err != nil || index < 0 || index >= len(trades) {
+		return fmt.Errorf("invalid rofi selection: %q", selected)
+	}
+	trade := trades[index]
+
+	d.log.Debug("Processing Rofi exit code",
+		"exit_code", exitCode,
+		"index", index,
+		"trade_id", trade.ID,
+		"player_name", trade.PlayerName)
 	switch exitCode {
 	case 10: // T pressed - Trade
 		if d.tradeHandler != nil {
-			d.log.Info("Trade action triggered", "selected", selected)
-			return d.tradeHandler(selected)
+			d.log.Info("Trade action triggered", "trade_id", trade.ID)
+			return d.tradeHandler(trade.ID)
 		}
 	case 11: // P pressed - Party
 		if d.partyHandler != nil {
-			d.log.Info("Party action triggered", "selected", selected)
-			return d.partyHandler(selected)
+			d.log.Info("Party action triggered", "trade_id", trade.ID)
+			return d.partyHandler(trade.ID)
 		}
 	case 12: // F pressed - Finish
 		if d.finishHandler != nil {
-			d.log.Info("Finish action triggered", "selected", selected)
-			return d.finishHandler(selected)
+			d.log.Info("Finish action triggered", "trade_id", trade.ID)
+			return d.finishHandler(trade.ID)
 		}
 	case 13: // D pressed - Delete
 		if d.deleteHandler != nil {
-			d.log.Info("Delete action triggered", "selected", selected)
-			return d.deleteHandler(selected)
+			d.log.Info("Delete action triggered", "trade_id", trade.ID)
+			return d.deleteHandler(trade.ID)
+		}
+	case 14: // B pressed - Block
+		if d.blockHandler != nil {
+			d.log.Info("Block action triggered", "trade_id", trade.ID)
+			return d.blockHandler(trade.ID)
 		}
 	}
 	d.log.Warn("Unhandled Rofi exit code", "exit_code", exitCode)
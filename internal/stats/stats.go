@@ -0,0 +1,182 @@
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"hypr-exiled/internal/models"
+)
+
+// SessionGap separates trading sessions: closed sales further apart than
+// this start a new session.
+const SessionGap = time.Hour
+
+// topItemCount is the number of best-selling items listed per summary.
+const topItemCount = 5
+
+// ItemCount is the number of completed sales of an item.
+type ItemCount struct {
+	Item  string `json:"item"`
+	Sales int    `json:"sales"`
+}
+
+// Summary aggregates the incoming trades of a session, day, league or all of them.
+type Summary struct {
+	Label    string             `json:"label"`
+	Trades   int                `json:"trades"` // closed incoming trades
+	Sales    int                `json:"sales"`  // completed ones
+	Declined int                `json:"declined"`
+	NoShows  int                `json:"no_shows"` // expired without the buyer showing up
+	Earned   map[string]float64 `json:"earned"`   // per currency type
+
+	AvgCompletionSeconds float64     `json:"avg_completion_seconds"`
+	DeclineRate          float64     `json:"decline_rate"`
+	NoShowRate           float64     `json:"no_show_rate"`
+	TopItems             []ItemCount `json:"top_items"`
+
+	completion time.Duration
+	items      map[string]int
+}
+
+// Report holds all summaries computed from the history.
+type Report struct {
+	Total    Summary   `json:"total"`
+	Sessions []Summary `json:"sessions"`
+	Days     []Summary `json:"days"`
+	Leagues  []Summary `json:"leagues"`
+}
+
+// Compute builds the report from history entries. Outgoing trades are
+// ignored, the report is about our sales.
+func Compute(history []models.HistoryEntry) Report {
+	var sales []models.HistoryEntry
+	for _, entry := range history {
+		if entry.IsIncoming() {
+			sales = append(sales, entry)
+		}
+	}
+	sort.Slice(sales, func(i, j int) bool {
+		return sales[i].ClosedAt.Before(sales[j].ClosedAt)
+	})
+
+	report := Report{Total: newSummary("total")}
+	days := make(map[string]*Summary)
+	leagues := make(map[string]*Summary)
+	var session *Summary
+	var sessionStart, lastClosed time.Time
+
+	for _, entry := range sales {
+		closed := entry.ClosedAt.Local()
+
+		if session == nil || closed.Sub(lastClosed) > SessionGap {
+			if session != nil {
+				session.Label = sessionLabel(sessionStart, lastClosed)
+				report.Sessions = append(report.Sessions, *session)
+			}
+			s := newSummary("")
+			session = &s
+			sessionStart = closed
+		}
+		lastClosed = closed
+
+		day := closed.Format("2006-01-02")
+		if days[day] == nil {
+			s := newSummary(day)
+			days[day] = &s
+		}
+		if leagues[entry.League] == nil {
+			s := newSummary(entry.League)
+			leagues[entry.League] = &s
+		}
+
+		for _, summary := range []*Summary{&report.Total, session, days[day], leagues[entry.League]} {
+			summary.add(entry)
+		}
+	}
+	if session != nil {
+		session.Label = sessionLabel(sessionStart, lastClosed)
+		report.Sessions = append(report.Sessions, *session)
+	}
+
+	report.Days = sortedSummaries(days)
+	report.Leagues = sortedSummaries(leagues)
+
+	report.Total.finish()
+	for _, list := range [][]Summary{report.Sessions, report.Days, report.Leagues} {
+		for i := range list {
+			list[i].finish()
+		}
+	}
+	return report
+}
+
+func newSummary(label string) Summary {
+	return Summary{
+		Label:  label,
+		Earned: make(map[string]float64),
+		items:  make(map[string]int),
+	}
+}
+
+func (s *Summary) add(entry models.HistoryEntry) {
+	s.Trades++
+	switch entry.Outcome {
+	case models.TradeStatusCompleted:
+		s.Sales++
+		s.Earned[models.NormalizeCurrency(entry.CurrencyType)] += entry.CurrencyAmount
+		s.completion += entry.Duration
+		s.items[entry.ItemName]++
+	case models.TradeStatusDeclined:
+		s.Declined++
+	case models.TradeStatusExpired:
+		s.NoShows++
+	}
+}
+
+// finish computes the averages, rates and top items.
+func (s *Summary) finish() {
+	if s.Sales > 0 {
+		s.AvgCompletionSeconds = (s.completion / time.Duration(s.Sales)).Seconds()
+	}
+	if s.Trades > 0 {
+		s.DeclineRate = float64(s.Declined) / float64(s.Trades)
+		s.NoShowRate = float64(s.NoShows) / float64(s.Trades)
+	}
+
+	s.TopItems = make([]ItemCount, 0, len(s.items))
+	for item, count := range s.items {
+		s.TopItems = append(s.TopItems, ItemCount{Item: item, Sales: count})
+	}
+	sort.Slice(s.TopItems, func(i, j int) bool {
+		if s.TopItems[i].Sales != s.TopItems[j].Sales {
+			return s.TopItems[i].Sales > s.TopItems[j].Sales
+		}
+		return s.TopItems[i].Item < s.TopItems[j].Item
+	})
+	if len(s.TopItems) > topItemCount {
+		s.TopItems = s.TopItems[:topItemCount]
+	}
+}
+
+// AvgCompletion returns the average time from whisper to completed sale.
+func (s Summary) AvgCompletion() time.Duration {
+	return time.Duration(s.AvgCompletionSeconds * float64(time.Second))
+}
+
+func sessionLabel(start, end time.Time) string {
+	if start.Format("2006-01-02") == end.Format("2006-01-02") {
+		return start.Format("2006-01-02 15:04") + "–" + end.Format("15:04")
+	}
+	return start.Format("2006-01-02 15:04") + "–" + end.Format("2006-01-02 15:04")
+}
+
+func sortedSummaries(summaries map[string]*Summary) []Summary {
+	list := make([]Summary, 0, len(summaries))
+	for _, s := range summaries {
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Label < list[j].Label
+	})
+	return list
+}
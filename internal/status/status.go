@@ -0,0 +1,162 @@
+package status
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ZoneType classifies the area the player is in.
+type ZoneType string
+
+const (
+	ZoneUnknown ZoneType = ""
+	ZoneHideout ZoneType = "hideout"
+	ZoneTown    ZoneType = "town"
+	ZoneMap     ZoneType = "map"
+	ZoneArea    ZoneType = "area" // campaign and other areas
+)
+
+// Status is a snapshot of the player's availability and location.
+type Status struct {
+	AFK  bool `json:"afk"`
+	DND  bool `json:"dnd"`
+	Busy bool `json:"busy"` // set by hand through IPC
+
+	Zone      string   `json:"zone"`
+	ZoneType  ZoneType `json:"zone_type"`
+	AreaID    string   `json:"area_id"`
+	AreaLevel int      `json:"area_level"`
+}
+
+// Away reports whether new incoming whispers should get an auto-reply.
+func (s Status) Away() bool {
+	return s.AFK || s.Busy
+}
+
+// Mapping reports whether the player is in a map.
+func (s Status) Mapping() bool {
+	return s.ZoneType == ZoneMap
+}
+
+// ZoneSummary describes the current zone, e.g. "Strand (map, level 83)".
+func (s Status) ZoneSummary() string {
+	if s.Zone == "" {
+		return "Zone unknown"
+	}
+	if s.AreaLevel == 0 {
+		return fmt.Sprintf("%s (%s)", s.Zone, s.ZoneType)
+	}
+	return fmt.Sprintf("%s (%s, level %d)", s.Zone, s.ZoneType, s.AreaLevel)
+}
+
+// Summary describes the whole status in one line.
+func (s Status) Summary() string {
+	var flags []string
+	if s.AFK {
+		flags = append(flags, "AFK")
+	}
+	if s.DND {
+		flags = append(flags, "DND")
+	}
+	if s.Busy {
+		flags = append(flags, "busy")
+	}
+	if len(flags) == 0 {
+		flags = append(flags, "available")
+	}
+	return fmt.Sprintf("%s | %s", s.ZoneSummary(), strings.Join(flags, ", "))
+}
+
+// ClassifyArea derives the zone type from the internal area id of the
+// "Generating level" line, falling back to the zone name.
+func ClassifyArea(areaID, zone string) ZoneType {
+	id := strings.ToLower(areaID)
+	switch {
+	case strings.Contains(id, "hideout"):
+		return ZoneHideout
+	case strings.Contains(id, "town"), strings.Contains(id, "kingsmarch"):
+		return ZoneTown
+	case strings.HasPrefix(id, "map"):
+		return ZoneMap
+	case id != "":
+		return ZoneArea
+	}
+
+	if strings.Contains(strings.ToLower(zone), "hideout") {
+		return ZoneHideout
+	}
+	return ZoneArea
+}
+
+// Tracker holds the availability and zone reported by the game log and the
+// busy flag toggled by the user.
+type Tracker struct {
+	mu     sync.RWMutex
+	status Status
+
+	// Announced by "Generating level", applied on "You have entered"
+	pendingAreaID    string
+	pendingAreaLevel int
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// Get returns the current status.
+func (t *Tracker) Get() Status {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.status
+}
+
+func (t *Tracker) SetAFK(on bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status.AFK = on
+}
+
+func (t *Tracker) SetDND(on bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status.DND = on
+}
+
+func (t *Tracker) SetBusy(on bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status.Busy = on
+}
+
+// ToggleBusy flips the busy flag and returns the new value.
+func (t *Tracker) ToggleBusy() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status.Busy = !t.status.Busy
+	return t.status.Busy
+}
+
+// SetArea records the area the game is about to load.
+func (t *Tracker) SetArea(areaID string, level int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pendingAreaID = areaID
+	t.pendingAreaLevel = level
+}
+
+// EnterZone records the zone the player entered, together with the area
+// announced by the preceding SetArea, and returns the new status.
+func (t *Tracker) EnterZone(zone string) Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.status.Zone = zone
+	t.status.AreaID = t.pendingAreaID
+	t.status.AreaLevel = t.pendingAreaLevel
+	t.status.ZoneType = ClassifyArea(t.pendingAreaID, zone)
+
+	t.pendingAreaID = ""
+	t.pendingAreaLevel = 0
+	return t.status
+}
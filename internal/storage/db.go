@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"hypr-exiled/internal/models"
 	"hypr-exiled/pkg/global"
+	"hypr-exiled/pkg/profile"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -17,33 +19,14 @@ type DB struct {
 	db *sql.DB
 }
 
-const schema = `
-CREATE TABLE IF NOT EXISTS trades (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    timestamp DATETIME NOT NULL,
-    trigger_type TEXT NOT NULL,
-    player_name TEXT NOT NULL,
-    item_name TEXT NOT NULL,
-    league TEXT NOT NULL,
-    currency_amount REAL NOT NULL,
-    currency_type TEXT NOT NULL,
-    stash_tab TEXT NOT NULL,
-    position_left INTEGER NOT NULL,
-    position_top INTEGER NOT NULL,
-    message TEXT NOT NULL,
-    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-);
-`
-
 func New() (*DB, error) {
-	// Get user config directory
-	configDir, err := os.UserConfigDir()
+	// The database lives in the config directory of the active profile
+	dbDir, err := profile.ConfigDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user config directory: %w", err)
+		return nil, err
 	}
 
 	// Ensure directory exists
-	dbDir := filepath.Join(configDir, "hypr-exiled")
 	if err := os.MkdirAll(dbDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
@@ -59,9 +42,10 @@ func New() (*DB, error) {
 		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
 	}
 
-	// Create schema
-	if _, err := db.Exec(schema); err != nil {
-		return nil, fmt.Errorf("failed to create schema: %w", err)
+	// Create or upgrade the schema
+	if err := migrate(db, dbPath); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
 	return &DB{db: db}, nil
@@ -71,42 +55,170 @@ func (d *DB) Close() error {
 	return d.db.Close()
 }
 
-func (d *DB) AddTrade(trade models.TradeEntry) error {
+// AddTrade stores a new trade in the "new" state and returns its id.
+func (d *DB) AddTrade(trade models.TradeEntry) (int64, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		INSERT INTO trades (
 			timestamp, trigger_type, player_name, item_name, league,
 			currency_amount, currency_type, stash_tab,
-			position_left, position_top, message
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			position_left, position_top, message,
+			item_amount, is_bulk, item_level, item_quality, note,
+			while_mapping, status
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := d.db.Exec(query,
+	result, err := tx.Exec(query,
 		trade.Timestamp, trade.TriggerType, trade.PlayerName,
 		trade.ItemName, trade.League, trade.CurrencyAmount,
 		trade.CurrencyType, trade.StashTab, trade.Position.Left,
-		trade.Position.Top, trade.Message)
+		trade.Position.Top, trade.Message,
+		trade.ItemAmount, trade.IsBulk, trade.ItemLevel,
+		trade.ItemQuality, trade.Note, trade.WhileMapping,
+		models.TradeStatusNew)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert trade: %w", err)
+	}
 
+	id, err := result.LastInsertId()
 	if err != nil {
-		return fmt.Errorf("failed to insert trade: %w", err)
+		return 0, fmt.Errorf("failed to get trade id: %w", err)
 	}
 
+	if err := addTradeEvent(tx, id, models.TradeStatusNew); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit trade: %w", err)
+	}
+	return id, nil
+}
+
+// PingTrade looks for an open trade repeating the given whisper: same
+// trigger, player, item and stash position, last whispered at or after since.
+// If there is one, its ping counter is increased and the updated trade is
+// returned.
+func (d *DB) PingTrade(trade models.TradeEntry, since time.Time) (models.TradeEntry, bool, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return models.TradeEntry{}, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	placeholders, args := statusList(models.OpenTradeStatuses)
+	var id int64
+	err = tx.QueryRow(`
+		SELECT id FROM trades
+		WHERE trigger_type = ?
+		AND player_name = ?
+		AND item_name = ?
+		AND item_amount = ?
+		AND stash_tab = ?
+		AND position_left = ?
+		AND position_top = ?
+		AND COALESCE(last_ping_at, created_at) >= ?
+		AND status IN (`+placeholders+`)
+		ORDER BY id DESC
+		LIMIT 1`,
+		append([]interface{}{
+			trade.TriggerType, trade.PlayerName, trade.ItemName, trade.ItemAmount,
+			trade.StashTab, trade.Position.Left, trade.Position.Top, since.UTC(),
+		}, args...)...).Scan(&id)
+	if err == sql.ErrNoRows {
+		return models.TradeEntry{}, false, nil
+	}
+	if err != nil {
+		return models.TradeEntry{}, false, fmt.Errorf("failed to look up repeated trade: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE trades
+		SET pings = pings + 1, last_ping_at = CURRENT_TIMESTAMP
+		WHERE id = ?`, id); err != nil {
+		return models.TradeEntry{}, false, fmt.Errorf("failed to count ping of trade %d: %w", id, err)
+	}
+
+	pinged, err := scanTrade(tx.QueryRow("SELECT "+tradeColumns+" FROM trades WHERE id = ?", id))
+	if err != nil {
+		return models.TradeEntry{}, false, fmt.Errorf("failed to get trade %d: %w", id, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.TradeEntry{}, false, fmt.Errorf("failed to commit ping: %w", err)
+	}
+	return pinged, true, nil
+}
+
+func addTradeEvent(tx *sql.Tx, tradeID int64, status models.TradeStatus) error {
+	if _, err := tx.Exec("INSERT INTO trade_events (trade_id, status) VALUES (?, ?)", tradeID, status); err != nil {
+		return fmt.Errorf("failed to record trade event: %w", err)
+	}
 	return nil
 }
 
+// statusList returns "?, ?, ..." placeholders and their arguments.
+func statusList(statuses []models.TradeStatus) (string, []interface{}) {
+	placeholders := make([]string, len(statuses))
+	args := make([]interface{}, len(statuses))
+	for i, status := range statuses {
+		placeholders[i] = "?"
+		args[i] = status
+	}
+	return strings.Join(placeholders, ", "), args
+}
+
+// tradeColumns are the columns read by scanTrade, in order.
+const tradeColumns = `
+        id, timestamp, trigger_type, player_name, item_name, league,
+        currency_amount, currency_type, stash_tab,
+        position_left, position_top, message,
+        created_at, item_amount, is_bulk,
+        item_level, item_quality, note, buyer_arrived,
+        while_mapping, status, pings,
+        COALESCE((SELECT list FROM player_lists WHERE player_lists.player_name = trades.player_name), '')`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTrade(row rowScanner) (models.TradeEntry, error) {
+	var trade models.TradeEntry
+	err := row.Scan(
+		&trade.ID, &trade.Timestamp, &trade.TriggerType, &trade.PlayerName,
+		&trade.ItemName, &trade.League, &trade.CurrencyAmount,
+		&trade.CurrencyType, &trade.StashTab, &trade.Position.Left,
+		&trade.Position.Top, &trade.Message, &trade.CreatedAt,
+		&trade.ItemAmount, &trade.IsBulk, &trade.ItemLevel,
+		&trade.ItemQuality, &trade.Note, &trade.BuyerArrived,
+		&trade.WhileMapping, &trade.Status, &trade.Pings, &trade.PlayerList)
+	return trade, err
+}
+
 func (d *DB) GetTrades() ([]models.TradeEntry, error) {
 	log := global.GetLogger()
 	log.Debug("Retrieving trades from database")
 
+	placeholders, args := statusList(models.OpenTradeStatuses)
 	query := `
-        SELECT timestamp, trigger_type, player_name, item_name, league,
-               currency_amount, currency_type, stash_tab,
-               position_left, position_top, message,
-               created_at
+        SELECT ` + tradeColumns + `
         FROM trades
-        ORDER BY timestamp DESC
+        WHERE status IN (` + placeholders + `)
+        ORDER BY buyer_arrived DESC,
+                 EXISTS (SELECT 1 FROM player_lists
+                         WHERE player_lists.player_name = trades.player_name
+                         AND list = ?) DESC,
+                 timestamp DESC
     `
 
-	rows, err := d.db.Query(query)
+	rows, err := d.db.Query(query, append(args, models.PlayerAllowed)...)
 	if err != nil {
 		log.Error("Failed to query trades", err)
 		return nil, fmt.Errorf("failed to query trades: %w", err)
@@ -115,25 +227,20 @@ func (d *DB) GetTrades() ([]models.TradeEntry, error) {
 
 	var trades []models.TradeEntry
 	for rows.Next() {
-		var trade models.TradeEntry
-		var timestamp, createdAt time.Time
-		err := rows.Scan(
-			&timestamp, &trade.TriggerType, &trade.PlayerName,
-			&trade.ItemName, &trade.League, &trade.CurrencyAmount,
-			&trade.CurrencyType, &trade.StashTab, &trade.Position.Left,
-			&trade.Position.Top, &trade.Message, &createdAt)
+		trade, err := scanTrade(rows)
 		if err != nil {
 			log.Error("Failed to scan trade", err)
 			return nil, fmt.Errorf("failed to scan trade: %w", err)
 		}
-		trade.Timestamp = timestamp
 
 		log.Debug("Retrieved trade",
+			"id", trade.ID,
+			"status", trade.Status,
 			"player_name", trade.PlayerName,
 			"item_name", trade.ItemName,
 			"trigger_type", trade.TriggerType,
-			"timestamp", timestamp,
-			"created_at", createdAt)
+			"timestamp", trade.Timestamp,
+			"created_at", trade.CreatedAt)
 
 		trades = append(trades, trade)
 	}
@@ -142,6 +249,19 @@ func (d *DB) GetTrades() ([]models.TradeEntry, error) {
 	return trades, nil
 }
 
+// GetTrade returns a single trade by id.
+func (d *DB) GetTrade(id int64) (models.TradeEntry, error) {
+	row := d.db.QueryRow("SELECT "+tradeColumns+" FROM trades WHERE id = ?", id)
+	trade, err := scanTrade(row)
+	if err == sql.ErrNoRows {
+		return models.TradeEntry{}, fmt.Errorf("trade %d not found", id)
+	}
+	if err != nil {
+		return models.TradeEntry{}, fmt.Errorf("failed to get trade %d: %w", id, err)
+	}
+	return trade, nil
+}
+
 func (d *DB) RemoveTrades(trades []models.TradeEntry) error {
 	tx, err := d.db.Begin()
 	if err != nil {
@@ -166,19 +286,265 @@ func (d *DB) RemoveTrades(trades []models.TradeEntry) error {
 	return tx.Commit()
 }
 
-func (d *DB) RemoveTradesByPlayer(playerName string) error {
-	_, err := d.db.Exec("DELETE FROM trades WHERE player_name = ?", playerName)
+// SetBuyerArrived flags the incoming trades of a player as arrived (or no
+// longer arrived) and returns the number of trades affected.
+func (d *DB) SetBuyerArrived(playerName string, arrived bool) (int64, error) {
+	result, err := d.db.Exec(`
+		UPDATE trades
+		SET buyer_arrived = ?
+		WHERE player_name = ?
+		AND trigger_type IN ('incoming_trade', 'incoming_bulk_trade')
+		AND buyer_arrived != ?
+		AND status NOT IN (?, ?, ?)`,
+		arrived, playerName, arrived,
+		models.TradeStatusCompleted, models.TradeStatusDeclined, models.TradeStatusExpired)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update arrival of player %s: %w", playerName, err)
+	}
+	return result.RowsAffected()
+}
+
+// SetTradeStatus moves the open trades of a player to a new state and records
+// each transition in trade_events. Only trades in one of the from states are
+// changed; without from states every open trade is. It returns the number of
+// trades changed.
+func (d *DB) SetTradeStatus(playerName string, status models.TradeStatus, from ...models.TradeStatus) (int64, error) {
+	return d.updateStatus("player_name = ?", playerName, status, from)
+}
+
+// SetTradeStatusByID moves a single trade to a new state like SetTradeStatus
+// and reports whether it was changed.
+func (d *DB) SetTradeStatusByID(id int64, status models.TradeStatus, from ...models.TradeStatus) (bool, error) {
+	updated, err := d.updateStatus("id = ?", id, status, from)
+	return updated > 0, err
+}
+
+func (d *DB) updateStatus(where string, key interface{}, status models.TradeStatus, from []models.TradeStatus) (int64, error) {
+	if len(from) == 0 {
+		from = models.OpenTradeStatuses
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	placeholders, args := statusList(from)
+	rows, err := tx.Query(`
+		SELECT id FROM trades
+		WHERE `+where+`
+		AND status != ?
+		AND status IN (`+placeholders+`)`,
+		append([]interface{}{key, status}, args...)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query trades: %w", err)
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan trade id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read trade ids: %w", err)
+	}
+
+	for _, id := range ids {
+		if _, err := tx.Exec("UPDATE trades SET status = ? WHERE id = ?", status, id); err != nil {
+			return 0, fmt.Errorf("failed to update trade %d: %w", id, err)
+		}
+		if err := addTradeEvent(tx, id, status); err != nil {
+			return 0, err
+		}
+		if !status.IsOpen() {
+			if err := archiveTrade(tx, id); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit status change: %w", err)
+	}
+	return int64(len(ids)), nil
+}
+
+// archiveTrade moves a closed trade into trade_history. Its events stay and
+// are linked through trade_id.
+func archiveTrade(tx *sql.Tx, id int64) error {
+	_, err := tx.Exec(`
+		INSERT INTO trade_history (
+			trade_id, trigger_type, player_name, item_name, item_amount,
+			is_bulk, league, currency_amount, currency_type, outcome,
+			received_at, closed_at, duration_seconds
+		)
+		SELECT id, trigger_type, player_name, item_name, item_amount,
+		       is_bulk, league, currency_amount, currency_type, status,
+		       created_at, CURRENT_TIMESTAMP,
+		       CAST(strftime('%s', 'now') - strftime('%s', created_at) AS INTEGER)
+		FROM trades
+		WHERE id = ?`, id)
 	if err != nil {
-		return fmt.Errorf("failed to delete trades for player %s: %w", playerName, err)
+		return fmt.Errorf("failed to archive trade %d: %w", id, err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM trades WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to remove archived trade %d: %w", id, err)
 	}
 	return nil
 }
 
-func (d *DB) Cleanup(olderThan time.Duration) error {
-	cutoff := time.Now().Add(-olderThan)
-	_, err := d.db.Exec("DELETE FROM trades WHERE created_at < ?", cutoff)
+// HistoryFilter narrows GetHistory. Zero values match everything.
+type HistoryFilter struct {
+	League string    // exact league name, case-insensitive
+	Item   string    // substring of the item name, case-insensitive
+	Since  time.Time // closed at or after
+	Until  time.Time // closed at or before
+}
+
+// GetHistory returns closed trades, newest first.
+func (d *DB) GetHistory(filter HistoryFilter) ([]models.HistoryEntry, error) {
+	query := `
+		SELECT id, trade_id, trigger_type, player_name, item_name, item_amount,
+		       is_bulk, league, currency_amount, currency_type, outcome,
+		       received_at, closed_at, duration_seconds
+		FROM trade_history
+		WHERE 1 = 1`
+	var args []interface{}
+
+	if filter.League != "" {
+		query += " AND league = ? COLLATE NOCASE"
+		args = append(args, filter.League)
+	}
+	if filter.Item != "" {
+		query += " AND item_name LIKE ? ESCAPE '\\'"
+		args = append(args, "%"+escapeLike(filter.Item)+"%")
+	}
+	if !filter.Since.IsZero() {
+		query += " AND closed_at >= ?"
+		args = append(args, filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		query += " AND closed_at <= ?"
+		args = append(args, filter.Until.UTC())
+	}
+	query += " ORDER BY closed_at DESC"
+
+	rows, err := d.db.Query(query, args...)
 	if err != nil {
-		return fmt.Errorf("failed to cleanup old trades: %w", err)
+		return nil, fmt.Errorf("failed to query trade history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []models.HistoryEntry
+	for rows.Next() {
+		var entry models.HistoryEntry
+		var seconds int64
+		err := rows.Scan(
+			&entry.ID, &entry.TradeID, &entry.TriggerType, &entry.PlayerName,
+			&entry.ItemName, &entry.ItemAmount, &entry.IsBulk, &entry.League,
+			&entry.CurrencyAmount, &entry.CurrencyType, &entry.Outcome,
+			&entry.ReceivedAt, &entry.ClosedAt, &seconds)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trade history: %w", err)
+		}
+		entry.Duration = time.Duration(seconds) * time.Second
+		history = append(history, entry)
+	}
+	return history, rows.Err()
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Cleanup closes open trades older than openTrades as expired and deletes
+// history entries older than history. A zero duration keeps everything.
+func (d *DB) Cleanup(openTrades, history time.Duration) error {
+	if openTrades > 0 {
+		if _, err := d.ExpireTrades(ExpiryFilter{Before: time.Now().Add(-openTrades)}); err != nil {
+			return err
+		}
+	}
+
+	if history > 0 {
+		cutoff := time.Now().Add(-history).UTC()
+		if _, err := d.db.Exec("DELETE FROM trade_history WHERE closed_at < ?", cutoff); err != nil {
+			return fmt.Errorf("failed to cleanup trade history: %w", err)
+		}
+	}
+
+	// Drop the events of trades that are gone from both tables
+	_, err := d.db.Exec(`
+		DELETE FROM trade_events
+		WHERE trade_id NOT IN (SELECT id FROM trades)
+		AND trade_id NOT IN (SELECT trade_id FROM trade_history)`)
+	if err != nil {
+		return fmt.Errorf("failed to cleanup trade events: %w", err)
 	}
 	return nil
 }
+
+// ExpiryFilter selects the open trades ExpireTrades closes.
+type ExpiryFilter struct {
+	Before       time.Time            // last whispered before
+	Statuses     []models.TradeStatus // only trades in these states, all open states if empty
+	IncomingOnly bool
+}
+
+// ExpireTrades closes the matching open trades as expired, moving them to the
+// history, and returns them as they were before.
+func (d *DB) ExpireTrades(filter ExpiryFilter) ([]models.TradeEntry, error) {
+	statuses := filter.Statuses
+	if len(statuses) == 0 {
+		statuses = models.OpenTradeStatuses
+	}
+
+	placeholders, args := statusList(statuses)
+	query := `
+		SELECT ` + tradeColumns + `
+		FROM trades
+		WHERE COALESCE(last_ping_at, created_at) < ?
+		AND status IN (` + placeholders + `)`
+	if filter.IncomingOnly {
+		query += " AND trigger_type IN ('incoming_trade', 'incoming_bulk_trade')"
+	}
+
+	rows, err := d.db.Query(query, append([]interface{}{filter.Before.UTC()}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stale trades: %w", err)
+	}
+
+	var stale []models.TradeEntry
+	for rows.Next() {
+		trade, err := scanTrade(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan trade: %w", err)
+		}
+		stale = append(stale, trade)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stale trades: %w", err)
+	}
+
+	var expired []models.TradeEntry
+	for _, trade := range stale {
+		updated, err := d.SetTradeStatusByID(trade.ID, models.TradeStatusExpired, statuses...)
+		if err != nil {
+			return expired, fmt.Errorf("failed to expire trade %d: %w", trade.ID, err)
+		}
+		if updated {
+			expired = append(expired, trade)
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"hypr-exiled/pkg/global"
+)
+
+// migration is one step of the schema history. Steps must be idempotent:
+// databases from before versioning may already contain some of the changes.
+type migration struct {
+	version     int
+	description string
+	up          func(tx *sql.Tx) error
+}
+
+// migrations are applied in order. Never change a released step, append a
+// new one instead.
+var migrations = []migration{
+	{1, "create trades table", execStatements(`
+		CREATE TABLE IF NOT EXISTS trades (
+		    id INTEGER PRIMARY KEY AUTOINCREMENT,
+		    timestamp DATETIME NOT NULL,
+		    trigger_type TEXT NOT NULL,
+		    player_name TEXT NOT NULL,
+		    item_name TEXT NOT NULL,
+		    league TEXT NOT NULL,
+		    currency_amount REAL NOT NULL,
+		    currency_type TEXT NOT NULL,
+		    stash_tab TEXT NOT NULL,
+		    position_left INTEGER NOT NULL,
+		    position_top INTEGER NOT NULL,
+		    message TEXT NOT NULL,
+		    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`)},
+	{2, "bulk trades", addColumns("trades",
+		column{"item_amount", "REAL NOT NULL DEFAULT 0"},
+		column{"is_bulk", "INTEGER NOT NULL DEFAULT 0"})},
+	{3, "listing qualifiers and buyer notes", addColumns("trades",
+		column{"item_level", "INTEGER NOT NULL DEFAULT 0"},
+		column{"item_quality", "INTEGER NOT NULL DEFAULT 0"},
+		column{"note", "TEXT NOT NULL DEFAULT ''"})},
+	{4, "buyer arrival", addColumns("trades",
+		column{"buyer_arrived", "INTEGER NOT NULL DEFAULT 0"})},
+	{5, "trades received while mapping", addColumns("trades",
+		column{"while_mapping", "INTEGER NOT NULL DEFAULT 0"})},
+	{6, "trade lifecycle", chain(
+		addColumns("trades", column{"status", "TEXT NOT NULL DEFAULT 'new'"}),
+		execStatements(`
+			CREATE TABLE IF NOT EXISTS trade_events (
+			    id INTEGER PRIMARY KEY AUTOINCREMENT,
+			    trade_id INTEGER NOT NULL,
+			    status TEXT NOT NULL,
+			    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_trade_events_trade_id ON trade_events (trade_id)`))},
+	{7, "trade history", execStatements(`
+		CREATE TABLE IF NOT EXISTS trade_history (
+		    id INTEGER PRIMARY KEY AUTOINCREMENT,
+		    trade_id INTEGER NOT NULL,
+		    trigger_type TEXT NOT NULL,
+		    player_name TEXT NOT NULL,
+		    item_name TEXT NOT NULL,
+		    item_amount REAL NOT NULL DEFAULT 0,
+		    is_bulk INTEGER NOT NULL DEFAULT 0,
+		    league TEXT NOT NULL,
+		    currency_amount REAL NOT NULL,
+		    currency_type TEXT NOT NULL,
+		    outcome TEXT NOT NULL,
+		    received_at DATETIME NOT NULL,
+		    closed_at DATETIME NOT NULL,
+		    duration_seconds INTEGER NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_trade_history_closed_at ON trade_history (closed_at)`)},
+	{8, "repeated whispers", addColumns("trades",
+		column{"pings", "INTEGER NOT NULL DEFAULT 1"},
+		column{"last_ping_at", "DATETIME"})},
+	{9, "player block- and allowlist", execStatements(`
+		CREATE TABLE IF NOT EXISTS player_lists (
+		    player_name TEXT PRIMARY KEY COLLATE NOCASE,
+		    list TEXT NOT NULL,
+		    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`)},
+}
+
+const schemaVersionTable = `
+CREATE TABLE IF NOT EXISTS schema_version (
+    version INTEGER NOT NULL,
+    description TEXT NOT NULL,
+    applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+// migrate brings the database up to the latest schema version. Pending steps
+// run in a single transaction, after a backup of an existing database.
+func migrate(db *sql.DB, dbPath string) error {
+	log := global.GetLogger()
+
+	if _, err := db.Exec(schemaVersionTable); err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+
+	current, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	var pending []migration
+	for _, m := range migrations {
+		if m.version > current {
+			pending = append(pending, m)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	existing, err := tableExists(db, "trades")
+	if err != nil {
+		return err
+	}
+	if existing {
+		backup, err := backupDatabase(db, dbPath, current)
+		if err != nil {
+			return err
+		}
+		log.Info("Backed up database before migration",
+			"backup", backup,
+			"from_version", current)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, m := range pending {
+		log.Info("Applying database migration",
+			"version", m.version,
+			"description", m.description)
+
+		if err := m.up(tx); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.description, err)
+		}
+		if _, err := tx.Exec("INSERT INTO schema_version (version, description) VALUES (?, ?)",
+			m.version, m.description); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migrations: %w", err)
+	}
+
+	log.Info("Database schema up to date", "version", pending[len(pending)-1].version)
+	return nil
+}
+
+// schemaVersion returns the latest applied migration, 0 for new databases
+// and databases from before versioning.
+func schemaVersion(db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	if err := db.QueryRow("SELECT MAX(version) FROM schema_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// backupDatabase writes a consistent copy of the database next to it. VACUUM
+// INTO includes pages still in the WAL, which a plain file copy would miss.
+func backupDatabase(db *sql.DB, dbPath string, version int) (string, error) {
+	backup := fmt.Sprintf("%s.v%d-%s.bak", dbPath, version, time.Now().Format("20060102-150405"))
+	if _, err := os.Stat(backup); err == nil {
+		return "", fmt.Errorf("backup %s already exists", backup)
+	}
+
+	if _, err := db.Exec("VACUUM INTO ?", backup); err != nil {
+		return "", fmt.Errorf("failed to back up database to %s: %w", backup, err)
+	}
+	return backup, nil
+}
+
+// column is a column added by a migration.
+type column struct {
+	name       string
+	definition string
+}
+
+func execStatements(statements ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// addColumns adds the columns that do not exist yet.
+func addColumns(table string, columns ...column) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, c := range columns {
+			exists, err := columnExists(tx, table, c.name)
+			if err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+
+			query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, c.name, c.definition)
+			if _, err := tx.Exec(query); err != nil {
+				return fmt.Errorf("failed to add column %s.%s: %w", table, c.name, err)
+			}
+		}
+		return nil
+	}
+}
+
+func chain(steps ...func(tx *sql.Tx) error) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, step := range steps {
+			if err := step(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// queryer is implemented by *sql.DB and *sql.Tx.
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func tableExists(q queryer, table string) (bool, error) {
+	var count int
+	err := q.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up table %s: %w", table, err)
+	}
+	return count > 0, nil
+}
+
+func columnExists(q queryer, table, column string) (bool, error) {
+	rows, err := q.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, fmt.Errorf("failed to scan column info: %w", err)
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
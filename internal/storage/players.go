@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"hypr-exiled/internal/models"
+)
+
+// SetPlayerList puts a player on the block- or allowlist, moving them off the
+// other list. Names are matched case-insensitively.
+func (d *DB) SetPlayerList(playerName string, list models.PlayerList) error {
+	_, err := d.db.Exec(`
+		INSERT INTO player_lists (player_name, list) VALUES (?, ?)
+		ON CONFLICT (player_name) DO UPDATE
+		SET list = excluded.list, created_at = CURRENT_TIMESTAMP`,
+		playerName, list)
+	if err != nil {
+		return fmt.Errorf("failed to put player %s on the %s list: %w", playerName, list, err)
+	}
+	return nil
+}
+
+// RemovePlayer takes a player off both lists and reports whether they were
+// on one.
+func (d *DB) RemovePlayer(playerName string) (bool, error) {
+	result, err := d.db.Exec("DELETE FROM player_lists WHERE player_name = ?", playerName)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove player %s from lists: %w", playerName, err)
+	}
+	removed, err := result.RowsAffected()
+	return removed > 0, err
+}
+
+// GetPlayerList returns the list a player is on, empty if none.
+func (d *DB) GetPlayerList(playerName string) (models.PlayerList, error) {
+	var list models.PlayerList
+	err := d.db.QueryRow("SELECT list FROM player_lists WHERE player_name = ?", playerName).Scan(&list)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up player %s: %w", playerName, err)
+	}
+	return list, nil
+}
+
+// GetListedPlayers returns the players on both lists, ordered by list and
+// name.
+func (d *DB) GetListedPlayers() ([]models.ListedPlayer, error) {
+	rows, err := d.db.Query(`
+		SELECT player_name, list, created_at
+		FROM player_lists
+		ORDER BY list, player_name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query player lists: %w", err)
+	}
+	defer rows.Close()
+
+	var players []models.ListedPlayer
+	for rows.Next() {
+		var player models.ListedPlayer
+		if err := rows.Scan(&player.Name, &player.List, &player.AddedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan listed player: %w", err)
+		}
+		players = append(players, player)
+	}
+	return players, rows.Err()
+}
@@ -0,0 +1,180 @@
+package trade_manager
+
+import (
+	"strings"
+	"time"
+
+	"hypr-exiled/internal/models"
+	"hypr-exiled/internal/storage"
+)
+
+// expiryInterval is how often the expiry worker looks for stale trades
+const expiryInterval = 30 * time.Second
+
+// Start runs the expiry worker, which closes trades that outlived the
+// retention config while the service is running.
+func (tm *TradeManager) Start() {
+	tm.mu.Lock()
+	if tm.stopChan != nil {
+		tm.mu.Unlock()
+		return
+	}
+	stopChan := make(chan struct{})
+	tm.stopChan = stopChan
+	tm.mu.Unlock()
+
+	tm.workers.Add(1)
+	go func() {
+		defer tm.workers.Done()
+
+		ticker := time.NewTicker(expiryInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopChan:
+				tm.log.Info("Trade expiry worker stopped")
+				return
+			case <-ticker.C:
+				tm.expireTrades()
+			}
+		}
+	}()
+}
+
+// Stop stops the expiry worker and waits for a running pass to finish.
+func (tm *TradeManager) Stop() {
+	tm.mu.Lock()
+	stopChan := tm.stopChan
+	tm.stopChan = nil
+	tm.mu.Unlock()
+
+	if stopChan == nil {
+		return
+	}
+
+	tm.log.Info("Stopping trade expiry worker")
+	close(stopChan)
+	tm.workers.Wait()
+}
+
+// expireTrades closes incoming trades nobody answered within
+// retention.unanswered, replying "sold" to their buyers, and any open trade
+// older than retention.open_trades.
+//
+// Sold replies type into the game chat, so they are only sent while the game
+// has focus. Otherwise they wait for the next trade action for at most
+// retention.unanswered, see sendPendingReplies. Each pass also sends the
+// held back auto-replies once the game has focus again.
+func (tm *TradeManager) expireTrades() {
+	retention := tm.cfg.GetRetention()
+
+	if retention.Unanswered > 0 {
+		expired, err := tm.db.ExpireTrades(storage.ExpiryFilter{
+			Before:       time.Now().Add(-retention.Unanswered),
+			Statuses:     []models.TradeStatus{models.TradeStatusNew},
+			IncomingOnly: true,
+		})
+		if err != nil {
+			tm.log.Error("Failed to expire unanswered trades", err)
+		}
+		for _, trade := range expired {
+			tm.log.Info("Unanswered trade expired", "trade_id", trade.ID, "player", trade.PlayerName)
+			if commands, ok := tm.soldCommands(trade); ok {
+				tm.queueReply("sold reply", trade.PlayerName, commands, retention.Unanswered)
+			}
+		}
+	}
+
+	if retention.OpenTrades > 0 {
+		expired, err := tm.db.ExpireTrades(storage.ExpiryFilter{Before: time.Now().Add(-retention.OpenTrades)})
+		if err != nil {
+			tm.log.Error("Failed to expire old trades", err)
+		}
+		if len(expired) > 0 {
+			tm.log.Info("Old trades expired", "trades", len(expired))
+		}
+	}
+
+	if tm.detector.IsFocused() {
+		tm.sendPendingReplies()
+	}
+}
+
+// pendingReply is a whisper held back until the game has focus.
+type pendingReply struct {
+	kind     string // for the log, e.g. "sold reply"
+	player   string
+	commands []string
+	expires  time.Time // dropped when still unsent by then
+}
+
+// queueReply holds back a whisper for at most maxAge, see sendPendingReplies.
+func (tm *TradeManager) queueReply(kind, player string, commands []string, maxAge time.Duration) {
+	tm.mu.Lock()
+	tm.pendingReplies = append(tm.pendingReplies, pendingReply{
+		kind:     kind,
+		player:   player,
+		commands: commands,
+		expires:  time.Now().Add(maxAge),
+	})
+	pending := len(tm.pendingReplies)
+	tm.mu.Unlock()
+
+	tm.log.Debug("Holding back whisper until the game has focus", "kind", kind, "player", player, "pending", pending)
+}
+
+// sendPendingReplies sends the whispers held back by queueReply and drops the
+// ones that waited too long. It runs while the game has focus: from the
+// expiry worker and after trade actions the user started.
+func (tm *TradeManager) sendPendingReplies() {
+	tm.mu.Lock()
+	pending := tm.pendingReplies
+	tm.pendingReplies = nil
+	tm.mu.Unlock()
+
+	now := time.Now()
+	for _, reply := range pending {
+		if now.After(reply.expires) {
+			tm.log.Info("Dropping stale whisper", "kind", reply.kind, "player", reply.player)
+			continue
+		}
+
+		tm.log.Info("Sending held back whisper", "kind", reply.kind, "player", reply.player)
+		if err := tm.input.ExecutePoECommands(reply.commands); err != nil {
+			tm.log.Error("Failed to send held back whisper", err, "kind", reply.kind, "player", reply.player)
+		}
+	}
+}
+
+// soldCommands returns the "sold" commands for the buyer of a closed incoming
+// trade. Without "sold" commands in the config, and for blocked players,
+// there is nothing to send.
+func (tm *TradeManager) soldCommands(trade models.TradeEntry) ([]string, bool) {
+	if !trade.IsIncoming() || trade.PlayerList == models.PlayerBlocked {
+		return nil, false
+	}
+
+	commands, ok := tm.cfg.GetCommands()["sold"]
+	if !ok || len(commands) == 0 {
+		return nil, false
+	}
+	for i := range commands {
+		commands[i] = strings.ReplaceAll(commands[i], "{player}", trade.PlayerName)
+	}
+	return commands, true
+}
+
+// replySold whispers the "sold" commands to the buyer of a closed incoming
+// trade right away, see soldCommands.
+func (tm *TradeManager) replySold(trade models.TradeEntry) {
+	commands, ok := tm.soldCommands(trade)
+	if !ok {
+		return
+	}
+
+	tm.log.Info("Sending sold reply", "trade_id", trade.ID, "player", trade.PlayerName)
+	if err := tm.input.ExecutePoECommands(commands); err != nil {
+		tm.log.Error("Failed to send sold reply", err, "player", trade.PlayerName)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"hypr-exiled/internal/models"
 	"hypr-exiled/internal/poe/window"
 	"hypr-exiled/internal/rofi"
+	"hypr-exiled/internal/status"
 	"hypr-exiled/internal/storage"
 	"hypr-exiled/pkg/global"
 	"hypr-exiled/pkg/notify"
@@ -22,9 +23,10 @@ func NewTradeManager(detector *window.Detector, input *input.Input) *TradeManage
 		log.Fatal("Failed to initialize storage", err)
 	}
 
-	// Cleanup old trades (older than 24 hours)
+	// Expire stale trades and prune the history as configured
 	go func() {
-		if err := db.Cleanup(24 * time.Hour); err != nil {
+		retention := cfg.GetRetention()
+		if err := db.Cleanup(retention.OpenTrades, retention.History); err != nil {
 			log.Error("Failed to cleanup old trades", err)
 		}
 	}()
@@ -37,14 +39,20 @@ func NewTradeManager(detector *window.Detector, input *input.Input) *TradeManage
 		detector: detector,
 		cfg:      cfg,
 		log:      log,
+		status:   status.NewTracker(),
+
+		autoReplied: make(map[string]time.Time),
+		sortMode:    cfg.GetSort().Mode,
 	}
 
 	// Initialize Rofi with handlers that have access to the TradeManager instance
 	rofiManager := rofi.NewTradeDisplayManager(
-		func(selected string) error { return tm.handleTrade(selected) },
-		func(selected string) error { return tm.handleParty(selected) },
-		func(selected string) error { return tm.handleFinish(selected) },
-		func(selected string) error { return tm.handleDelete(selected) },
+		func(tradeID int64) error { return tm.handleTrade(tradeID) },
+		func(tradeID int64) error { return tm.handleParty(tradeID) },
+		func(tradeID int64) error { return tm.handleFinish(tradeID) },
+		func(tradeID int64) error { return tm.handleDelete(tradeID) },
+		func(tradeID int64) error { return tm.handleBlock(tradeID) },
+		tm.handleSort,
 	)
 
 	tm.rofi = rofiManager
@@ -52,39 +60,275 @@ func NewTradeManager(detector *window.Detector, input *input.Input) *TradeManage
 }
 
 func (tm *TradeManager) AddTrade(trade models.TradeEntry) error {
+	if trade.IsIncoming() && tm.status.Get().Mapping() {
+		trade.WhileMapping = true
+	}
+
+	if duplicates := tm.cfg.GetDuplicates(); duplicates.Window > 0 {
+		pinged, ok, err := tm.db.PingTrade(trade, time.Now().Add(-duplicates.Window))
+		if err != nil {
+			tm.log.Error("Failed to check for repeated whisper", err)
+		} else if ok {
+			tm.log.Info("Repeated whisper", "trade_id", pinged.ID, "player", pinged.PlayerName, "pings", pinged.Pings)
+			tm.announceTrade(pinged, !duplicates.Silent)
+			return nil
+		}
+	}
+
+	list, err := tm.db.GetPlayerList(trade.PlayerName)
+	if err != nil {
+		tm.log.Error("Failed to look up player list", err, "player", trade.PlayerName)
+	}
+
 	tm.log.Debug("Adding trade", "trade", trade)
-	if err := tm.db.AddTrade(trade); err != nil {
+	id, err := tm.db.AddTrade(trade)
+	if err != nil {
 		tm.log.Error("Failed to add trade", err)
 		return fmt.Errorf("failed to add trade: %w", err)
 	}
+	trade.ID = id
+	trade.Status = models.TradeStatusNew
+	trade.PlayerList = list
 
-	var notificationMsg string
-	if trade.TriggerType == "incoming_trade" {
-		notificationMsg = fmt.Sprintf("@%s wants to buy %s for %.0f %s",
-			trade.PlayerName,
-			trade.ItemName,
-			trade.CurrencyAmount,
-			trade.CurrencyType)
+	tm.announceTrade(trade, true)
 
-		// Play notification sound for incoming trades
+	tm.log.Info("Trade added successfully", "trade", trade)
+	return nil
+}
+
+// announceTrade plays the trade sound, shows the notification and sends the
+// auto-reply for a new or repeated whisper. Whispers of blocked players are
+// only stored.
+func (tm *TradeManager) announceTrade(trade models.TradeEntry, sound bool) {
+	if trade.IsIncoming() && trade.PlayerList == models.PlayerBlocked {
+		tm.log.Info("Muted whisper of blocked player", "trade_id", trade.ID, "player", trade.PlayerName)
+		return
+	}
+
+	if trade.IsIncoming() && sound {
+		// Play notification sound for incoming trades, friends get their own
 		if notifier := global.GetSoundNotifier(); notifier != nil {
-			if err := notifier.PlayTradeSound(); err != nil {
+			play := notifier.PlayTradeSound
+			if trade.PlayerList == models.PlayerAllowed {
+				play = notifier.PlayFriendSound
+			}
+			if err := play(); err != nil {
 				tm.log.Error("Failed to play trade sound", err)
 			}
 		}
-	} else {
-		notificationMsg = fmt.Sprintf("Trade request for %s sent to @%s",
-			trade.ItemName,
-			trade.PlayerName,
-		)
 	}
 
-	if err := global.GetNotifier().Show(notificationMsg, notify.Info); err != nil {
+	if err := global.GetNotifier().Show(NotificationMessage(trade), notify.Info); err != nil {
 		tm.log.Error("Failed to send trade notification", err)
 	}
 
-	tm.log.Info("Trade added successfully", "trade", trade)
-	return nil
+	if trade.IsIncoming() && tm.status.Get().Away() {
+		// Typing takes a while, keep it off the log watcher
+		go tm.autoReply(trade.PlayerName)
+	}
+}
+
+// HandleLogEvent updates pending trades from game events. A buyer joining the
+// area marks their incoming trades as arrived, leaving clears the mark.
+func (tm *TradeManager) HandleLogEvent(event models.LogEvent) {
+	switch event.Type {
+	case models.EventPlayerJoined:
+		updated, err := tm.db.SetBuyerArrived(event.PlayerName, true)
+		if err != nil {
+			tm.log.Error("Failed to mark buyer as arrived", err, "player", event.PlayerName)
+			return
+		}
+		if updated == 0 {
+			return
+		}
+
+		tm.log.Info("Buyer arrived", "player", event.PlayerName, "trades", updated)
+		tm.setPlayerStatus(event.PlayerName, models.TradeStatusInHideout,
+			models.TradeStatusNew, models.TradeStatusInvited)
+		if list, err := tm.db.GetPlayerList(event.PlayerName); err == nil && list == models.PlayerBlocked {
+			return // Blocked buyers are muted
+		}
+		if tm.cfg.GetArrivalSound() {
+			if notifier := global.GetSoundNotifier(); notifier != nil {
+				if err := notifier.PlayArrivalSound(); err != nil {
+					tm.log.Error("Failed to play arrival sound", err)
+				}
+			}
+		}
+		if err := tm.notify.Show(fmt.Sprintf("@%s arrived in your hideout", event.PlayerName), notify.Info); err != nil {
+			tm.log.Error("Failed to send arrival notification", err)
+		}
+
+	case models.EventPlayerLeft:
+		if _, err := tm.db.SetBuyerArrived(event.PlayerName, false); err != nil {
+			tm.log.Error("Failed to clear buyer arrival", err, "player", event.PlayerName)
+		}
+		tm.setPlayerStatus(event.PlayerName, models.TradeStatusInvited, models.TradeStatusInHideout)
+
+	case models.EventTradeAccepted:
+		trade, ok := tm.takeTradePartner(true)
+		if !ok {
+			return
+		}
+
+		tm.log.Info("Trade accepted, finishing trade", "trade_id", trade.ID, "player", trade.PlayerName)
+		if err := tm.finishTrade(trade); err != nil {
+			tm.log.Error("Failed to finish accepted trade", err, "trade_id", trade.ID)
+			return
+		}
+		if err := tm.notify.Show(fmt.Sprintf("Trade with @%s completed", trade.PlayerName), notify.Info); err != nil {
+			tm.log.Error("Failed to send trade completion notification", err)
+		}
+
+	case models.EventAFKChanged:
+		tm.log.Info("AFK mode changed", "enabled", event.Enabled)
+		tm.status.SetAFK(event.Enabled)
+
+	case models.EventDNDChanged:
+		tm.log.Info("DND mode changed", "enabled", event.Enabled)
+		tm.status.SetDND(event.Enabled)
+
+	case models.EventAreaGenerated:
+		tm.status.SetArea(event.AreaID, event.AreaLevel)
+
+	case models.EventZoneEntered:
+		current := tm.status.EnterZone(event.Zone)
+		tm.log.Info("Zone changed",
+			"zone", current.Zone,
+			"zone_type", current.ZoneType,
+			"area_id", current.AreaID,
+			"area_level", current.AreaLevel)
+
+	case models.EventTradeCancelled:
+		trade, ok := tm.takeTradePartner(false)
+		if !ok {
+			return
+		}
+
+		tm.log.Info("Trade cancelled", "trade_id", trade.ID, "player", trade.PlayerName)
+		tm.setStatus(trade.ID, models.TradeStatusInHideout, models.TradeStatusTrading)
+		if err := tm.notify.Show(fmt.Sprintf("Trade with @%s was cancelled, it stays open", trade.PlayerName), notify.Info); err != nil {
+			tm.log.Error("Failed to send trade cancellation notification", err)
+		}
+	}
+}
+
+// tradeOutcomeWindow is how long after a /tradewith an accepted or cancelled
+// trade window is still attributed to that player.
+const tradeOutcomeWindow = 10 * time.Minute
+
+// takeTradePartner returns the trade of the last /tradewith if it is recent
+// enough to own the trade window outcome. A completed trade clears it.
+func (tm *TradeManager) takeTradePartner(completed bool) (models.TradeEntry, bool) {
+	tm.mu.Lock()
+	tradeID := tm.lastTradeID
+	startedAt := tm.lastTradeAt
+	if completed || time.Since(startedAt) > tradeOutcomeWindow {
+		tm.lastTradeID = 0
+	}
+	tm.mu.Unlock()
+
+	if tradeID == 0 {
+		tm.log.Debug("Trade window outcome without a pending /tradewith")
+		return models.TradeEntry{}, false
+	}
+	if time.Since(startedAt) > tradeOutcomeWindow {
+		tm.log.Debug("Ignoring trade window outcome of a stale /tradewith",
+			"trade_id", tradeID,
+			"trade_started", startedAt)
+		return models.TradeEntry{}, false
+	}
+
+	trade, err := tm.db.GetTrade(tradeID)
+	if err != nil {
+		tm.log.Error("Failed to get trade of the last /tradewith", err, "trade_id", tradeID)
+		return models.TradeEntry{}, false
+	}
+	return trade, true
+}
+
+// Status returns the tracker of the player's availability.
+func (tm *TradeManager) Status() *status.Tracker {
+	return tm.status
+}
+
+const (
+	// defaultAutoReply is used when the config has no "auto_reply" commands
+	defaultAutoReply = "@{player} busy right now, will invite you in a few minutes"
+	// autoReplyCooldown is the minimum time between two auto-replies to a player
+	autoReplyCooldown = 10 * time.Minute
+)
+
+// autoReply answers a buyer while we are AFK or busy, at most once per
+// player within autoReplyCooldown. Without focus on the game the reply waits
+// for it, see queueReply, instead of pulling the game to the front.
+func (tm *TradeManager) autoReply(playerName string) {
+	now := time.Now()
+
+	tm.mu.Lock()
+	for player, at := range tm.autoReplied {
+		if now.Sub(at) > autoReplyCooldown {
+			delete(tm.autoReplied, player)
+		}
+	}
+	if _, replied := tm.autoReplied[playerName]; replied {
+		tm.mu.Unlock()
+		tm.log.Debug("Skipping auto-reply, player was answered recently", "player", playerName)
+		return
+	}
+	tm.autoReplied[playerName] = now
+	tm.mu.Unlock()
+
+	commands, ok := tm.cfg.GetCommands()["auto_reply"]
+	if !ok {
+		commands = []string{defaultAutoReply}
+	}
+	for i := range commands {
+		commands[i] = strings.ReplaceAll(commands[i], "{player}", playerName)
+	}
+
+	if !tm.detector.IsFocused() {
+		tm.queueReply("auto-reply", playerName, commands, autoReplyCooldown)
+		return
+	}
+
+	tm.log.Info("Sending auto-reply", "player", playerName, "status", tm.status.Get())
+	if err := tm.input.ExecutePoECommands(commands); err != nil {
+		tm.log.Error("Failed to send auto-reply", err, "player", playerName)
+	}
+}
+
+// NotificationMessage builds the desktop notification text for a trade.
+func NotificationMessage(trade models.TradeEntry) string {
+	var message string
+	switch {
+	case trade.IsIncoming():
+		message = fmt.Sprintf("@%s wants to buy %s for %g %s",
+			trade.PlayerName,
+			trade.ItemDescription(),
+			trade.CurrencyAmount,
+			trade.CurrencyType)
+	case trade.IsBulk:
+		message = fmt.Sprintf("Bulk request for %s sent to @%s",
+			trade.ItemDescription(),
+			trade.PlayerName)
+	default:
+		message = fmt.Sprintf("Trade request for %s sent to @%s",
+			trade.ItemDescription(),
+			trade.PlayerName,
+		)
+	}
+
+	if trade.PlayerList == models.PlayerAllowed {
+		message = "★ " + message
+	}
+	if trade.Pings > 1 {
+		message += fmt.Sprintf(" (x%d)", trade.Pings)
+	}
+	if trade.Note != "" {
+		message += fmt.Sprintf("\n“%s”", trade.Note)
+	}
+	return message
 }
 
 func (tm *TradeManager) ShowTrades() error {
@@ -99,6 +343,9 @@ func (tm *TradeManager) ShowTrades() error {
 		tm.log.Error("Failed to get trades", err)
 		return fmt.Errorf("failed to get trades: %w", err)
 	}
+	trades = visibleTrades(trades)
+	mode := tm.SortMode()
+	sortTrades(trades, mode, tm.cfg.GetSort().Rates)
 
 	if len(trades) == 0 {
 		tm.notify.Show("No trades to display", notify.Info)
@@ -106,18 +353,9 @@ func (tm *TradeManager) ShowTrades() error {
 		return nil
 	}
 
-	// Format trades for Rofi
-	var options []string
-	for i, trade := range trades {
-		formattedTrade := tm.rofi.FormatTrade(trade, i)
-		options = append(options, formattedTrade)
-		tm.log.Debug("Adding trade to options",
-			"index", i,
-			"player_name", trade.PlayerName)
-	}
-
 	tm.log.Info("Displaying trades in Rofi", "trade_count", len(trades))
-	if err := tm.rofi.DisplayTrades(options); err != nil {
+	header := fmt.Sprintf("%s · sorted by %s", tm.status.Get().ZoneSummary(), mode)
+	if err := tm.rofi.DisplayTrades(trades, header); err != nil {
 		tm.log.Error("Failed to display trades in Rofi", err)
 		return fmt.Errorf("failed to show trades in rofi: %w", err)
 	}
@@ -125,41 +363,48 @@ func (tm *TradeManager) ShowTrades() error {
 	return nil
 }
 
-func (tm *TradeManager) handleTrade(selected string) error {
-	playerName, err := tm.rofi.ExtractPlayerName(selected)
+func (tm *TradeManager) handleTrade(tradeID int64) error {
+	trade, err := tm.db.GetTrade(tradeID)
 	if err != nil {
-		return fmt.Errorf("failed to extract player name: %w", err)
+		return err
 	}
 
 	commands := tm.cfg.GetCommands()["trade"]
 	for i := range commands {
-		commands[i] = strings.ReplaceAll(commands[i], "{player}", playerName)
+		commands[i] = strings.ReplaceAll(commands[i], "{player}", trade.PlayerName)
 	}
 
 	if err := tm.input.ExecutePoECommands(commands); err != nil {
 		return fmt.Errorf("failed to execute trade commands: %w", err)
 	}
+	tm.sendPendingReplies()
+
+	tm.mu.Lock()
+	tm.lastTradeID = trade.ID
+	tm.lastTradeAt = time.Now()
+	tm.mu.Unlock()
 
+	tm.setStatus(trade.ID, models.TradeStatusTrading)
 	return nil
 }
 
-func (tm *TradeManager) handleParty(selected string) error {
-	tm.log.Debug("Handling party request", "selected_trade", selected)
+func (tm *TradeManager) handleParty(tradeID int64) error {
+	tm.log.Debug("Handling party request", "trade_id", tradeID)
 
-	playerName, err := tm.rofi.ExtractPlayerName(selected)
+	trade, err := tm.db.GetTrade(tradeID)
 	if err != nil {
-		tm.log.Error("Failed to extract player name", err)
-		return fmt.Errorf("failed to extract player name: %w", err)
+		tm.log.Error("Failed to get trade", err, "trade_id", tradeID)
+		return err
 	}
 
-	tm.log.Debug("Extracted player name for party", "player_name", playerName)
+	tm.log.Debug("Inviting player to party", "player_name", trade.PlayerName)
 
 	commands := tm.cfg.GetCommands()["party"]
 	tm.log.Debug("Original commands", "commands", commands) // Log original commands
 
 	for i := range commands {
 		originalCmd := commands[i]
-		commands[i] = strings.ReplaceAll(commands[i], "{player}", playerName)
+		commands[i] = strings.ReplaceAll(commands[i], "{player}", trade.PlayerName)
 
 		tm.log.Debug("Preparing party command",
 			"original_command", originalCmd,
@@ -172,53 +417,91 @@ func (tm *TradeManager) handleParty(selected string) error {
 		tm.log.Error("Failed to execute party commands", err)
 		return fmt.Errorf("failed to execute party commands: %w", err)
 	}
+	tm.sendPendingReplies()
 
+	tm.setStatus(trade.ID, models.TradeStatusInvited, models.TradeStatusNew)
 	return nil
 }
 
-func (tm *TradeManager) handleFinish(selected string) error {
-	playerName, err := tm.rofi.ExtractPlayerName(selected)
+func (tm *TradeManager) handleFinish(tradeID int64) error {
+	trade, err := tm.db.GetTrade(tradeID)
 	if err != nil {
-		return fmt.Errorf("failed to extract player name: %w", err)
+		return err
 	}
 
+	return tm.finishTrade(trade)
+}
+
+// finishTrade runs the finish commands for the trade's player and completes it.
+func (tm *TradeManager) finishTrade(trade models.TradeEntry) error {
 	commands := tm.cfg.GetCommands()["finish"]
 	for i := range commands {
-		commands[i] = strings.ReplaceAll(commands[i], "{player}", playerName)
+		commands[i] = strings.ReplaceAll(commands[i], "{player}", trade.PlayerName)
 	}
 
 	if err := tm.input.ExecutePoECommands(commands); err != nil {
 		return fmt.Errorf("failed to execute finish commands: %w", err)
 	}
+	tm.sendPendingReplies()
 
-	// Remove trade from database
-	if err := tm.db.RemoveTradesByPlayer(playerName); err != nil {
-		return fmt.Errorf("failed to remove trades: %w", err)
+	if _, err := tm.db.SetTradeStatusByID(trade.ID, models.TradeStatusCompleted); err != nil {
+		return fmt.Errorf("failed to complete trade: %w", err)
 	}
 
 	return nil
 }
 
-func (tm *TradeManager) handleDelete(selected string) error {
-	tm.log.Info("Delete action triggered", "selected", selected)
+func (tm *TradeManager) handleDelete(tradeID int64) error {
+	tm.log.Info("Delete action triggered", "trade_id", tradeID)
 
-	playerName, err := tm.rofi.ExtractPlayerName(selected)
+	trade, err := tm.db.GetTrade(tradeID)
 	if err != nil {
-		tm.log.Error("Failed to extract player name", err, "selected", selected)
-		return fmt.Errorf("failed to extract player name: %w", err)
+		tm.log.Error("Failed to get trade", err, "trade_id", tradeID)
+		return err
 	}
 
-	if err := tm.db.RemoveTradesByPlayer(playerName); err != nil {
-		tm.log.Error("Failed to delete trade", err, "player_name", playerName)
-		return fmt.Errorf("failed to delete trade: %w", err)
+	if _, err := tm.db.SetTradeStatusByID(tradeID, models.TradeStatusDeclined); err != nil {
+		tm.log.Error("Failed to decline trade", err, "trade_id", tradeID)
+		return fmt.Errorf("failed to decline trade: %w", err)
 	}
+	tm.replySold(trade)
+	tm.sendPendingReplies()
 
 	tm.ShowTrades()
 
-	tm.log.Info("Trade deleted from the database", "player_name", playerName)
+	tm.log.Info("Trade declined", "trade_id", tradeID)
 	return nil
 }
 
+// setStatus moves a single trade to a new state, see
+// storage.DB.SetTradeStatusByID. Failures are only logged, the action that
+// caused the transition already happened.
+func (tm *TradeManager) setStatus(tradeID int64, status models.TradeStatus, from ...models.TradeStatus) {
+	if _, err := tm.db.SetTradeStatusByID(tradeID, status, from...); err != nil {
+		tm.log.Error("Failed to update trade status", err,
+			"trade_id", tradeID,
+			"status", status)
+	}
+}
+
+// setPlayerStatus moves the open trades of a player to a new state, for game
+// events that only name the player.
+func (tm *TradeManager) setPlayerStatus(playerName string, status models.TradeStatus, from ...models.TradeStatus) {
+	updated, err := tm.db.SetTradeStatus(playerName, status, from...)
+	if err != nil {
+		tm.log.Error("Failed to update trade status", err,
+			"player", playerName,
+			"status", status)
+		return
+	}
+	if updated > 0 {
+		tm.log.Debug("Trade status updated",
+			"player", playerName,
+			"status", status,
+			"trades", updated)
+	}
+}
+
 func (m *TradeManager) Close() error {
 	m.log.Info("Closing TradeManager")
 	return m.db.Close()
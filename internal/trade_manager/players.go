@@ -0,0 +1,81 @@
+package trade_manager
+
+import (
+	"fmt"
+
+	"hypr-exiled/internal/models"
+	"hypr-exiled/pkg/notify"
+)
+
+// BlockPlayer puts a player on the blocklist. Their whispers are still
+// stored, but hidden from the trades UI and muted.
+func (tm *TradeManager) BlockPlayer(playerName string) error {
+	if err := tm.db.SetPlayerList(playerName, models.PlayerBlocked); err != nil {
+		tm.log.Error("Failed to block player", err, "player", playerName)
+		return err
+	}
+	tm.log.Info("Player blocked", "player", playerName)
+	return nil
+}
+
+// AllowPlayer puts a player on the allowlist. Their whispers are marked and
+// play the friend sound.
+func (tm *TradeManager) AllowPlayer(playerName string) error {
+	if err := tm.db.SetPlayerList(playerName, models.PlayerAllowed); err != nil {
+		tm.log.Error("Failed to allow player", err, "player", playerName)
+		return err
+	}
+	tm.log.Info("Player allowed", "player", playerName)
+	return nil
+}
+
+// UnlistPlayer takes a player off the block- and allowlist and reports
+// whether they were on one.
+func (tm *TradeManager) UnlistPlayer(playerName string) (bool, error) {
+	removed, err := tm.db.RemovePlayer(playerName)
+	if err != nil {
+		tm.log.Error("Failed to unlist player", err, "player", playerName)
+		return false, err
+	}
+	if removed {
+		tm.log.Info("Player unlisted", "player", playerName)
+	}
+	return removed, nil
+}
+
+// ListedPlayers returns the players on the block- and allowlist.
+func (tm *TradeManager) ListedPlayers() ([]models.ListedPlayer, error) {
+	return tm.db.GetListedPlayers()
+}
+
+// handleBlock blocks the buyer of the selected trade, which hides all their
+// trades, and shows the remaining ones again.
+func (tm *TradeManager) handleBlock(tradeID int64) error {
+	trade, err := tm.db.GetTrade(tradeID)
+	if err != nil {
+		tm.log.Error("Failed to get trade", err, "trade_id", tradeID)
+		return err
+	}
+
+	if err := tm.BlockPlayer(trade.PlayerName); err != nil {
+		return fmt.Errorf("failed to block player: %w", err)
+	}
+	if err := tm.notify.Show(fmt.Sprintf("Blocked @%s", trade.PlayerName), notify.Info); err != nil {
+		tm.log.Error("Failed to send block notification", err)
+	}
+
+	tm.ShowTrades()
+	return nil
+}
+
+// visibleTrades drops the incoming trades of blocked players.
+func visibleTrades(trades []models.TradeEntry) []models.TradeEntry {
+	visible := trades[:0]
+	for _, trade := range trades {
+		if trade.IsIncoming() && trade.PlayerList == models.PlayerBlocked {
+			continue
+		}
+		visible = append(visible, trade)
+	}
+	return visible
+}
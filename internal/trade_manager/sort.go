@@ -0,0 +1,89 @@
+package trade_manager
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"hypr-exiled/internal/models"
+	"hypr-exiled/pkg/config"
+)
+
+// Sort modes of the trades UI, see config.SortModes
+const (
+	SortArrived = "arrived" // buyers in the hideout, then allowlisted players, then newest
+	SortNewest  = "newest"
+	SortOldest  = "oldest" // fair queue
+	SortValue   = "value"  // highest price in the reference currency first
+	SortPlayer  = "player" // trades of a player together, most recent player first
+)
+
+// SortMode returns the current sort mode of the trades UI.
+func (tm *TradeManager) SortMode() string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.sortMode
+}
+
+// NextSortMode switches the trades UI to the next sort mode and returns it.
+func (tm *TradeManager) NextSortMode() string {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	next := (slices.Index(config.SortModes, tm.sortMode) + 1) % len(config.SortModes)
+	tm.sortMode = config.SortModes[next]
+	return tm.sortMode
+}
+
+// handleSort cycles the sort mode and shows the trades again.
+func (tm *TradeManager) handleSort() error {
+	mode := tm.NextSortMode()
+	tm.log.Info("Sort mode changed", "mode", mode)
+	return tm.ShowTrades()
+}
+
+// sortTrades orders trades in place. Trades are read from the database in
+// the arrived order, which also breaks ties of the other modes.
+func sortTrades(trades []models.TradeEntry, mode string, rates map[string]float64) {
+	switch mode {
+	case SortNewest:
+		sort.SliceStable(trades, func(i, j int) bool {
+			return trades[i].Timestamp.After(trades[j].Timestamp)
+		})
+	case SortOldest:
+		sort.SliceStable(trades, func(i, j int) bool {
+			return trades[i].Timestamp.Before(trades[j].Timestamp)
+		})
+	case SortValue:
+		normalized := make(map[string]float64, len(rates))
+		for currency, rate := range rates {
+			normalized[models.NormalizeCurrency(currency)] = rate
+		}
+		value := func(trade models.TradeEntry) float64 {
+			// Unknown currencies are worth nothing and go last
+			return trade.CurrencyAmount * normalized[models.NormalizeCurrency(trade.CurrencyType)]
+		}
+		sort.SliceStable(trades, func(i, j int) bool {
+			return value(trades[i]) > value(trades[j])
+		})
+	case SortPlayer:
+		// Rank players by their latest whisper
+		latest := make(map[string]time.Time)
+		for _, trade := range trades {
+			if trade.Timestamp.After(latest[trade.PlayerName]) {
+				latest[trade.PlayerName] = trade.Timestamp
+			}
+		}
+		sort.SliceStable(trades, func(i, j int) bool {
+			a, b := trades[i], trades[j]
+			if a.PlayerName == b.PlayerName {
+				return a.Timestamp.After(b.Timestamp)
+			}
+			la, lb := latest[a.PlayerName], latest[b.PlayerName]
+			if !la.Equal(lb) {
+				return la.After(lb)
+			}
+			return a.PlayerName < b.PlayerName
+		})
+	}
+}
@@ -2,10 +2,12 @@ package trade_manager
 
 import (
 	"sync"
+	"time"
 
 	"hypr-exiled/internal/input"
 	"hypr-exiled/internal/poe/window"
 	"hypr-exiled/internal/rofi"
+	"hypr-exiled/internal/status"
 	"hypr-exiled/internal/storage"
 	"hypr-exiled/pkg/config"
 	"hypr-exiled/pkg/logger"
@@ -34,6 +36,24 @@ type TradeManager struct {
 	input    *input.Input
 	cfg      *config.Config
 	notify   *notify.NotifyService
+	status   *status.Tracker
+
+	// Trade of the last /tradewith, to attribute trade window outcomes
+	lastTradeID int64
+	lastTradeAt time.Time
+
+	// Last auto-reply per player, so nobody is answered twice
+	autoReplied map[string]time.Time
+
+	// Current order of the trades UI, see sortTrades
+	sortMode string
+
+	// Expiry worker, see Start
+	stopChan chan struct{}
+	workers  sync.WaitGroup
+
+	// Whispers waiting for the game to have focus, see queueReply
+	pendingReplies []pendingReply
 }
 
 type Currency struct {
@@ -1,9 +1,13 @@
 package wm
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
-	"os/exec"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -11,7 +15,11 @@ import (
 	"hypr-exiled/pkg/notify"
 )
 
+// hyprlandTimeout bounds a single request on the Hyprland socket
+const hyprlandTimeout = 2 * time.Second
+
 type Hyprland struct {
+	socketPath       string // request socket (.socket.sock) of this Hyprland instance
 	hasLoggedWaiting bool
 	lastFoundWindow  Window
 }
@@ -19,15 +27,60 @@ type Hyprland struct {
 func NewHyprland() (*Hyprland, error) {
 	log := global.GetLogger()
 
-	// Check if hyprctl is available
-	path, err := exec.LookPath("hyprctl")
+	socketPath, err := hyprlandSocket(".socket.sock")
 	if err != nil {
-		log.Error("hyprctl not found in PATH", err)
-		return nil, fmt.Errorf("hyprctl not found in PATH: %w", err)
+		log.Error("Hyprland socket not found", err)
+		return nil, err
+	}
+	log.Debug("Found Hyprland socket", "path", socketPath)
+
+	return &Hyprland{socketPath: socketPath}, nil
+}
+
+// hyprlandSocket returns the path of a socket of the running Hyprland
+// instance. Hyprland 0.40 moved the sockets from /tmp/hypr to
+// $XDG_RUNTIME_DIR/hypr; both are tried.
+func hyprlandSocket(name string) (string, error) {
+	signature := os.Getenv("HYPRLAND_INSTANCE_SIGNATURE")
+	if signature == "" {
+		return "", fmt.Errorf("HYPRLAND_INSTANCE_SIGNATURE is not set")
+	}
+
+	var candidates []string
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		candidates = append(candidates, filepath.Join(runtimeDir, "hypr", signature, name))
 	}
-	log.Debug("Found hyprctl", "path", path)
+	candidates = append(candidates, filepath.Join("/tmp", "hypr", signature, name))
 
-	return &Hyprland{}, nil
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no Hyprland socket %s found in %s", name, strings.Join(candidates, ", "))
+}
+
+// request sends a command to the Hyprland socket, as hyprctl does, and
+// returns the reply. Hyprland answers once and closes the connection.
+func (h *Hyprland) request(command string) ([]byte, error) {
+	conn, err := net.DialTimeout("unix", h.socketPath, hyprlandTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Hyprland socket: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(hyprlandTimeout)); err != nil {
+		return nil, fmt.Errorf("failed to set Hyprland socket deadline: %w", err)
+	}
+	if _, err := conn.Write([]byte(command)); err != nil {
+		return nil, fmt.Errorf("failed to send Hyprland request: %w", err)
+	}
+
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Hyprland reply: %w", err)
+	}
+	return reply, nil
 }
 
 func (h *Hyprland) Name() string {
@@ -38,12 +91,11 @@ func (h *Hyprland) FindWindow(classNames []string) (Window, error) {
 	log := global.GetLogger()
 	notifier := global.GetNotifier()
 
-	// Create the command
-	cmd := exec.Command("hyprctl", "clients", "-j")
-	output, err := cmd.CombinedOutput()
+	// "j/" asks for JSON, like hyprctl -j
+	output, err := h.request("j/clients")
 	if err != nil {
-		log.Error("Failed to execute hyprctl", err, "output", string(output))
-		return Window{}, fmt.Errorf("hyprctl error: %w", err)
+		log.Error("Failed to query Hyprland clients", err)
+		return Window{}, fmt.Errorf("hyprland clients error: %w", err)
 	}
 
 	if len(output) == 0 {
@@ -56,8 +108,8 @@ func (h *Hyprland) FindWindow(classNames []string) (Window, error) {
 	}
 
 	if err := json.Unmarshal(output, &windows); err != nil {
-		log.Error("Failed to parse hyprctl output", err, "output", string(output))
-		return Window{}, fmt.Errorf("failed to parse hyprctl output: %w", err)
+		log.Error("Failed to parse Hyprland clients", err, "output", string(output))
+		return Window{}, fmt.Errorf("failed to parse Hyprland clients: %w", err)
 	}
 
 	// Search for matching window
@@ -104,12 +156,92 @@ func (h *Hyprland) FocusWindow(w Window) error {
 
 	log.Debug("Focusing window", "address", w.Address)
 
-	cmd := exec.Command("hyprctl", "dispatch", "focuswindow", "address:"+w.Address)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		log.Error("Failed to focus window", err, "output", string(output))
+	reply, err := h.request("dispatch focuswindow address:" + w.Address)
+	if err != nil {
+		log.Error("Failed to focus window", err)
 		return fmt.Errorf("failed to focus window: %w", err)
 	}
+	if result := strings.TrimSpace(string(reply)); result != "ok" {
+		log.Error("Failed to focus window", nil, "reply", result)
+		return fmt.Errorf("failed to focus window: %s", result)
+	}
 
 	time.Sleep(100 * time.Millisecond)
 	return nil
 }
+
+// WatchEvents follows the Hyprland event socket (.socket2.sock), which sends
+// one "EVENT>>DATA" line per event.
+func (h *Hyprland) WatchEvents(stop <-chan struct{}, handle func(WindowEvent)) error {
+	log := global.GetLogger()
+
+	socketPath, err := hyprlandSocket(".socket2.sock")
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout("unix", socketPath, hyprlandTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Hyprland event socket: %w", err)
+	}
+	defer conn.Close()
+
+	// Unblock the reader when asked to stop
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	log.Info("Listening to Hyprland window events", "path", socketPath)
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		if event, ok := parseHyprlandEvent(scanner.Text()); ok {
+			handle(event)
+		}
+	}
+
+	select {
+	case <-stop:
+		return nil
+	default:
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("hyprland event socket error: %w", err)
+	}
+	return fmt.Errorf("hyprland event socket closed")
+}
+
+// parseHyprlandEvent turns the window events of the event socket into
+// WindowEvents. Addresses are sent without the 0x prefix of j/clients.
+func parseHyprlandEvent(line string) (WindowEvent, bool) {
+	name, data, ok := strings.Cut(line, ">>")
+	if !ok {
+		return WindowEvent{}, false
+	}
+
+	switch name {
+	case "openwindow":
+		// ADDRESS,WORKSPACENAME,WINDOWCLASS,WINDOWTITLE
+		fields := strings.SplitN(data, ",", 4)
+		if len(fields) < 3 {
+			return WindowEvent{}, false
+		}
+		return WindowEvent{Type: WindowOpened, Address: "0x" + fields[0], Class: fields[2]}, true
+	case "closewindow":
+		return WindowEvent{Type: WindowClosed, Address: "0x" + data}, true
+	case "activewindowv2":
+		// Empty when no window has focus
+		address := ""
+		if data != "" && data != "," {
+			address = "0x" + data
+		}
+		return WindowEvent{Type: WindowFocused, Address: address}, true
+	}
+	return WindowEvent{}, false
+}
@@ -0,0 +1,366 @@
+package wm
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"hypr-exiled/pkg/global"
+	"hypr-exiled/pkg/notify"
+)
+
+// i3 IPC message types, shared by Sway
+const (
+	i3RunCommand uint32 = 0
+	i3Subscribe  uint32 = 2
+	i3GetTree    uint32 = 4
+
+	// Events have the highest bit set; workspace events are type 0, window
+	// events type 3
+	i3WorkspaceEvent uint32 = 1<<31 | 0
+	i3WindowEvent    uint32 = 1<<31 | 3
+)
+
+const (
+	i3Magic   = "i3-ipc"
+	i3Timeout = 2 * time.Second
+)
+
+// I3 talks to i3 (X11) and Sway (Wayland) over their shared IPC protocol.
+// Windows are identified by their container id, kept in Window.Address.
+type I3 struct {
+	name             string // "Sway" or "i3", for logging
+	socketPath       string
+	hasLoggedWaiting bool
+	lastFoundWindow  Window
+}
+
+// i3Node is the part of a GET_TREE node needed to find windows
+type i3Node struct {
+	ID               int64  `json:"id"`
+	AppID            string `json:"app_id"` // Sway, native Wayland windows
+	WindowProperties struct {
+		Class string `json:"class"` // X11 and XWayland windows
+	} `json:"window_properties"`
+	Nodes         []i3Node `json:"nodes"`
+	FloatingNodes []i3Node `json:"floating_nodes"`
+}
+
+// class returns the Wayland app_id or the X11 class of the window.
+func (n i3Node) class() string {
+	if n.AppID != "" {
+		return n.AppID
+	}
+	return n.WindowProperties.Class
+}
+
+func NewI3() (*I3, error) {
+	log := global.GetLogger()
+
+	name := "i3"
+	if os.Getenv("SWAYSOCK") != "" {
+		name = "Sway"
+	}
+
+	socketPath, err := i3SocketPath()
+	if err != nil {
+		log.Error("i3/Sway IPC socket not found", err)
+		return nil, err
+	}
+	log.Debug("Found i3/Sway IPC socket", "name", name, "path", socketPath)
+
+	return &I3{name: name, socketPath: socketPath}, nil
+}
+
+// i3SocketPath returns the IPC socket of the running Sway or i3: $SWAYSOCK,
+// $I3SOCK, or what "i3 --get-socketpath" reports.
+func i3SocketPath() (string, error) {
+	for _, env := range []string{"SWAYSOCK", "I3SOCK"} {
+		if path := os.Getenv(env); path != "" {
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+		}
+	}
+
+	if _, err := exec.LookPath("i3"); err == nil {
+		out, err := exec.Command("i3", "--get-socketpath").Output()
+		if path := strings.TrimSpace(string(out)); err == nil && path != "" {
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no i3/Sway IPC socket found (SWAYSOCK, I3SOCK, i3 --get-socketpath)")
+}
+
+func (w *I3) Name() string {
+	return w.name
+}
+
+// writeI3Message sends one message: magic, payload length, type, payload.
+func writeI3Message(conn net.Conn, msgType uint32, payload string) error {
+	header := make([]byte, len(i3Magic)+8)
+	copy(header, i3Magic)
+	binary.LittleEndian.PutUint32(header[len(i3Magic):], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(header[len(i3Magic)+4:], msgType)
+
+	if _, err := conn.Write(append(header, payload...)); err != nil {
+		return fmt.Errorf("failed to send i3 message: %w", err)
+	}
+	return nil
+}
+
+// readI3Message reads one reply or event.
+func readI3Message(conn net.Conn) (uint32, []byte, error) {
+	header := make([]byte, len(i3Magic)+8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return 0, nil, fmt.Errorf("failed to read i3 message header: %w", err)
+	}
+	if string(header[:len(i3Magic)]) != i3Magic {
+		return 0, nil, fmt.Errorf("invalid i3 message header")
+	}
+
+	length := binary.LittleEndian.Uint32(header[len(i3Magic):])
+	msgType := binary.LittleEndian.Uint32(header[len(i3Magic)+4:])
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return 0, nil, fmt.Errorf("failed to read i3 message: %w", err)
+	}
+	return msgType, payload, nil
+}
+
+// request sends a message on a new connection and returns the reply.
+func (w *I3) request(msgType uint32, payload string) ([]byte, error) {
+	conn, err := net.DialTimeout("unix", w.socketPath, i3Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s IPC socket: %w", w.name, err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(i3Timeout)); err != nil {
+		return nil, fmt.Errorf("failed to set %s IPC deadline: %w", w.name, err)
+	}
+	if err := writeI3Message(conn, msgType, payload); err != nil {
+		return nil, err
+	}
+
+	replyType, reply, err := readI3Message(conn)
+	if err != nil {
+		return nil, err
+	}
+	if replyType != msgType {
+		return nil, fmt.Errorf("unexpected %s reply type %d to %d", w.name, replyType, msgType)
+	}
+	return reply, nil
+}
+
+func (w *I3) FindWindow(classNames []string) (Window, error) {
+	log := global.GetLogger()
+	notifier := global.GetNotifier()
+
+	reply, err := w.request(i3GetTree, "")
+	if err != nil {
+		log.Error("Failed to get window tree", err, "wm", w.name)
+		return Window{}, fmt.Errorf("%s tree error: %w", w.name, err)
+	}
+
+	var root i3Node
+	if err := json.Unmarshal(reply, &root); err != nil {
+		log.Error("Failed to parse window tree", err, "wm", w.name)
+		return Window{}, fmt.Errorf("failed to parse %s tree: %w", w.name, err)
+	}
+
+	if node, ok := findI3Node(root, classNames); ok {
+		foundWindow := Window{
+			Class:   node.class(),
+			Address: strconv.FormatInt(node.ID, 10),
+		}
+
+		// Only log if this is a different window than last time
+		if foundWindow != w.lastFoundWindow {
+			log.Debug("Found matching window by class",
+				"class", foundWindow.Class,
+				"con_id", foundWindow.Address)
+			w.lastFoundWindow = foundWindow
+		}
+
+		w.hasLoggedWaiting = false
+		return foundWindow, nil
+	}
+
+	// Reset last found window when no window is found
+	if w.lastFoundWindow != (Window{}) {
+		w.lastFoundWindow = Window{}
+	}
+
+	if !w.hasLoggedWaiting {
+		var message = "Waiting for PoE Window..."
+		log.Info(message)
+		notifier.Show(message, notify.Info)
+		w.hasLoggedWaiting = true
+	}
+
+	return Window{}, nil
+}
+
+// findI3Node searches the tree depth-first for a window whose class contains
+// one of the class names.
+func findI3Node(node i3Node, classNames []string) (i3Node, bool) {
+	if class := strings.ToLower(node.class()); class != "" {
+		for _, name := range classNames {
+			if strings.Contains(class, strings.ToLower(name)) {
+				return node, true
+			}
+		}
+	}
+
+	for _, children := range [][]i3Node{node.Nodes, node.FloatingNodes} {
+		for _, child := range children {
+			if found, ok := findI3Node(child, classNames); ok {
+				return found, true
+			}
+		}
+	}
+	return i3Node{}, false
+}
+
+func (w *I3) FocusWindow(win Window) error {
+	log := global.GetLogger()
+
+	log.Debug("Focusing window", "con_id", win.Address)
+
+	reply, err := w.request(i3RunCommand, fmt.Sprintf("[con_id=%s] focus", win.Address))
+	if err != nil {
+		log.Error("Failed to focus window", err)
+		return fmt.Errorf("failed to focus window: %w", err)
+	}
+
+	var results []struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(reply, &results); err != nil {
+		return fmt.Errorf("failed to parse focus reply: %w", err)
+	}
+	for _, result := range results {
+		if !result.Success {
+			log.Error("Failed to focus window", nil, "reply", result.Error)
+			return fmt.Errorf("failed to focus window: %s", result.Error)
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	return nil
+}
+
+// WatchEvents subscribes to window and workspace events on a dedicated
+// connection. Workspace events are needed because switching to an empty
+// workspace sends no window event.
+func (w *I3) WatchEvents(stop <-chan struct{}, handle func(WindowEvent)) error {
+	log := global.GetLogger()
+
+	conn, err := net.DialTimeout("unix", w.socketPath, i3Timeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s IPC socket: %w", w.name, err)
+	}
+	defer conn.Close()
+
+	// Unblock the reader when asked to stop
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	if err := writeI3Message(conn, i3Subscribe, `["window","workspace"]`); err != nil {
+		return err
+	}
+
+	log.Info("Listening to window events", "wm", w.name, "path", w.socketPath)
+
+	for {
+		msgType, payload, err := readI3Message(conn)
+		if err != nil {
+			select {
+			case <-stop:
+				return nil
+			default:
+			}
+			return fmt.Errorf("%s event stream error: %w", w.name, err)
+		}
+
+		switch msgType {
+		case i3Subscribe:
+			var result struct {
+				Success bool `json:"success"`
+			}
+			if err := json.Unmarshal(payload, &result); err != nil || !result.Success {
+				return fmt.Errorf("%s refused the window event subscription", w.name)
+			}
+		case i3WindowEvent:
+			if event, ok := parseI3WindowEvent(payload); ok {
+				handle(event)
+			}
+		case i3WorkspaceEvent:
+			if event, ok := parseI3WorkspaceEvent(payload); ok {
+				handle(event)
+			}
+		}
+	}
+}
+
+// parseI3WindowEvent turns the new, close and focus window events into
+// WindowEvents.
+func parseI3WindowEvent(payload []byte) (WindowEvent, bool) {
+	var event struct {
+		Change    string `json:"change"`
+		Container i3Node `json:"container"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return WindowEvent{}, false
+	}
+
+	address := strconv.FormatInt(event.Container.ID, 10)
+	switch event.Change {
+	case "new":
+		return WindowEvent{Type: WindowOpened, Address: address, Class: event.Container.class()}, true
+	case "close":
+		return WindowEvent{Type: WindowClosed, Address: address}, true
+	case "focus":
+		return WindowEvent{Type: WindowFocused, Address: address}, true
+	}
+	return WindowEvent{}, false
+}
+
+// parseI3WorkspaceEvent reports a focused workspace without a focused window,
+// i.e. an empty one, as focus leaving all windows. Non-empty workspaces are
+// followed by a window focus event.
+func parseI3WorkspaceEvent(payload []byte) (WindowEvent, bool) {
+	var event struct {
+		Change  string `json:"change"`
+		Current *struct {
+			Focus []int64 `json:"focus"` // children in focus order
+		} `json:"current"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return WindowEvent{}, false
+	}
+
+	if event.Change != "focus" || event.Current == nil || len(event.Current.Focus) > 0 {
+		return WindowEvent{}, false
+	}
+	return WindowEvent{Type: WindowFocused, Address: ""}, true
+}
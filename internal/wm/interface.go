@@ -21,3 +21,27 @@ type Window struct {
 func (w Window) IsEmpty() bool {
 	return w.ID == "" && w.Address == "" && w.Class == ""
 }
+
+// EventSource is implemented by window managers that report window changes
+// as they happen. The detector polls FindWindow for the others.
+type EventSource interface {
+	// WatchEvents calls handle for every window opened, closed or focused
+	// until stop is closed. It returns an error when the event stream breaks.
+	WatchEvents(stop <-chan struct{}, handle func(WindowEvent)) error
+}
+
+// WindowEventType identifies what happened to a window
+type WindowEventType string
+
+const (
+	WindowOpened  WindowEventType = "opened"
+	WindowClosed  WindowEventType = "closed"
+	WindowFocused WindowEventType = "focused"
+)
+
+// WindowEvent is a window change reported by an EventSource
+type WindowEvent struct {
+	Type    WindowEventType
+	Address string // same form as Window.Address
+	Class   string // only set for opened windows
+}
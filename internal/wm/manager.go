@@ -11,7 +11,20 @@ type Manager struct {
 	wm WindowManager
 }
 
-// NewManager creates a new window manager based on the session type
+// Backends selectable with the window_manager config, see
+// config.WindowManagers
+const (
+	BackendAuto     = "auto"
+	BackendHyprland = "hyprland"
+	BackendSway     = "sway"
+	BackendI3       = "i3"
+	BackendToplevel = "toplevel" // wlroots or KDE toplevel management
+	BackendX11      = "x11"      // EWMH, falling back to xdotool
+	BackendXdotool  = "xdotool"
+)
+
+// NewManager creates a new window manager based on the session type, or the
+// backend forced by the window_manager config
 func NewManager() (*Manager, error) {
 	log := global.GetLogger()
 
@@ -19,32 +32,67 @@ func NewManager() (*Manager, error) {
 	sessionType := os.Getenv("XDG_SESSION_TYPE")
 	log.Info("Session type detected", "session", sessionType)
 
-	var wm WindowManager
-	var err error
+	backend := global.GetConfig().GetWindowManager()
+	if backend == BackendAuto {
+		var err error
+		if backend, err = detectBackend(sessionType); err != nil {
+			return nil, err
+		}
+	} else {
+		log.Info("Window manager backend forced by config", "backend", backend)
+	}
 
+	log.Debug("Initializing compositor support", "type", backend)
+	wm, err := newBackend(backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize %s support: %w", backend, err)
+	}
+
+	log.Info("Window manager initialized", "name", wm.Name())
+	return &Manager{wm: wm}, nil
+}
+
+// detectBackend picks the backend for the session: the IPC of Hyprland or
+// Sway, else the toplevel management protocols on Wayland; i3 over its IPC
+// socket, else EWMH on X11.
+func detectBackend(sessionType string) (string, error) {
 	switch sessionType {
 	case "wayland":
-		if sig := os.Getenv("HYPRLAND_INSTANCE_SIGNATURE"); sig != "" {
-			log.Debug("Initializing compositor support", "type", "Hyprland")
-			wm, err = NewHyprland()
-			if err != nil {
-				return nil, fmt.Errorf("failed to initialize Hyprland support: %w", err)
-			}
-		} else {
-			return nil, fmt.Errorf("unsupported Wayland compositor: only Hyprland is supported")
+		switch {
+		case os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") != "":
+			return BackendHyprland, nil
+		case os.Getenv("SWAYSOCK") != "":
+			return BackendSway, nil
+		default:
+			// Fails if the compositor advertises no toplevel management
+			return BackendToplevel, nil
 		}
 	case "x11":
-		log.Debug("Initializing compositor support", "type", "X11")
-		wm, err = NewX11()
-		if err != nil {
-			return nil, fmt.Errorf("failed to initialize X11 support: %w", err)
+		if _, err := i3SocketPath(); err == nil {
+			return BackendI3, nil
 		}
+		return BackendX11, nil
 	default:
-		return nil, fmt.Errorf("unsupported session type: %s", sessionType)
+		return "", fmt.Errorf("unsupported session type: %s", sessionType)
 	}
+}
 
-	log.Info("Window manager initialized", "name", wm.Name())
-	return &Manager{wm: wm}, nil
+// newBackend creates the window manager of a backend.
+func newBackend(backend string) (WindowManager, error) {
+	switch backend {
+	case BackendHyprland:
+		return NewHyprland()
+	case BackendSway, BackendI3:
+		return NewI3()
+	case BackendToplevel:
+		return NewToplevel()
+	case BackendX11:
+		return NewX11()
+	case BackendXdotool:
+		return NewXdotool()
+	default:
+		return nil, fmt.Errorf("unknown window manager backend %q", backend)
+	}
 }
 
 // FindWindow wraps the underlying window manager's FindWindow method
@@ -57,6 +105,13 @@ func (m *Manager) FocusWindow(w Window) error {
 	return m.wm.FocusWindow(w)
 }
 
+// Events returns the event stream of the underlying window manager, if it
+// has one
+func (m *Manager) Events() (EventSource, bool) {
+	source, ok := m.wm.(EventSource)
+	return source, ok
+}
+
 // GetWMName returns the name of the current window manager
 func (m *Manager) GetWMName() string {
 	return m.wm.Name()
@@ -0,0 +1,408 @@
+package wm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hypr-exiled/pkg/global"
+	"hypr-exiled/pkg/notify"
+)
+
+// Toplevel management protocols, in the order they are tried
+const (
+	wlrToplevelManager = "zwlr_foreign_toplevel_manager_v1"
+	kdeWindowManager   = "org_kde_plasma_window_management"
+)
+
+// zwlr_foreign_toplevel_manager_v1 and zwlr_foreign_toplevel_handle_v1
+const (
+	wlrManagerToplevel uint16 = 0 // event: new handle
+	wlrManagerFinished uint16 = 1 // event: no more handles will be sent
+
+	wlrHandleAppID  uint16 = 1 // event
+	wlrHandleState  uint16 = 4 // event: array of states
+	wlrHandleDone   uint16 = 5 // event: state changes applied
+	wlrHandleClosed uint16 = 6 // event
+
+	wlrHandleActivate uint16 = 4 // request: activate(seat)
+	wlrHandleDestroy  uint16 = 7 // request
+
+	wlrStateActivated uint32 = 2
+)
+
+// org_kde_plasma_window_management and org_kde_plasma_window
+const (
+	kdeManagerWindow         uint16 = 1 // event: window(internal id)
+	kdeManagerWindowWithUUID uint16 = 4 // event: window_with_uuid(internal id, uuid), since 13
+
+	kdeManagerGetWindow uint16 = 1 // request: get_window(new id, internal id)
+
+	kdeWindowAppID        uint16 = 1 // event
+	kdeWindowStateChanged uint16 = 2 // event: state flags
+	kdeWindowUnmapped     uint16 = 5 // event
+	kdeWindowInitialState uint16 = 6 // event: all initial state was sent, since 4
+
+	kdeWindowSetState uint16 = 0 // request: set_state(flags, state)
+	kdeWindowDestroy  uint16 = 7 // request
+
+	kdeStateActive uint32 = 1
+)
+
+// Toplevel lists and activates windows through a Wayland toplevel management
+// protocol, for compositors without an IPC of their own (river, niri,
+// Wayfire, KDE Plasma): zwlr_foreign_toplevel_manager_v1 where available,
+// org_kde_plasma_window_management on KDE. A reader goroutine keeps the list
+// of toplevels up to date; Window.Address is the object id of a toplevel on
+// this connection.
+type Toplevel struct {
+	conn    *waylandConn
+	kde     bool
+	version uint32 // bound version of the manager
+	manager uint32
+	seat    uint32 // wl_seat passed to wlroots activate requests
+
+	mu        sync.Mutex
+	toplevels map[uint32]*toplevel
+	kdeIDs    map[uint32]bool   // KDE internal ids already requested
+	handler   func(WindowEvent) // set while WatchEvents runs
+	broken    chan struct{}     // closed when the connection fails
+	err       error             // why the connection failed
+	pending   []WindowEvent     // window events of the current dispatch
+
+	hasLoggedWaiting bool
+	lastFoundWindow  Window
+}
+
+// toplevel is the state of one window
+type toplevel struct {
+	appID         string
+	active        bool
+	pendingActive bool // wlroots: activated state until the next done event
+	ready         bool // initial state received
+	mapped        bool // opened event sent
+}
+
+func NewToplevel() (*Toplevel, error) {
+	log := global.GetLogger()
+
+	conn, err := dialWayland()
+	if err != nil {
+		log.Error("Failed to connect to Wayland compositor", err)
+		return nil, err
+	}
+
+	t := &Toplevel{
+		conn:      conn,
+		toplevels: make(map[uint32]*toplevel),
+		kdeIDs:    make(map[uint32]bool),
+		broken:    make(chan struct{}),
+	}
+
+	if t.manager, t.version, err = conn.bind(wlrToplevelManager, 3); err == nil {
+		// activate needs a seat; any seat does
+		if t.seat, _, err = conn.bind("wl_seat", 1); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("cannot activate windows: %w", err)
+		}
+	} else if t.manager, t.version, err = conn.bind(kdeWindowManager, 16); err == nil {
+		t.kde = true
+	} else {
+		conn.Close()
+		return nil, fmt.Errorf("unsupported Wayland compositor: it advertises neither %s nor %s",
+			wlrToplevelManager, kdeWindowManager)
+	}
+
+	// Receive the existing toplevels, KDE windows need a second roundtrip
+	// for the state of the windows requested in the first one
+	for range 2 {
+		if err := conn.roundtrip(t.dispatch); err != nil {
+			conn.Close()
+			log.Error("Failed to list toplevels", err, "wm", t.Name())
+			return nil, fmt.Errorf("failed to list toplevels: %w", err)
+		}
+	}
+	t.pending = nil
+	log.Debug("Toplevel management bound", "wm", t.Name(), "toplevels", len(t.toplevels))
+
+	go t.readEvents()
+	return t, nil
+}
+
+func (t *Toplevel) Name() string {
+	if t.kde {
+		return "KDE Plasma"
+	}
+	return "wlroots"
+}
+
+// readEvents dispatches events until the connection fails.
+func (t *Toplevel) readEvents() {
+	for {
+		event, err := t.conn.readEvent()
+		if err == nil {
+			err = t.dispatch(event)
+		}
+		if err != nil {
+			global.GetLogger().Error("Toplevel event stream broken", err, "wm", t.Name())
+			t.mu.Lock()
+			t.err = err
+			t.mu.Unlock()
+			close(t.broken)
+			t.conn.Close()
+			return
+		}
+
+		t.mu.Lock()
+		events, handler := t.pending, t.handler
+		t.pending = nil
+		t.mu.Unlock()
+
+		if handler != nil {
+			for _, e := range events {
+				handler(e)
+			}
+		}
+	}
+}
+
+// dispatch applies an event to the toplevel list and queues the window
+// events it causes in t.pending.
+func (t *Toplevel) dispatch(event waylandEvent) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if event.object == t.manager {
+		return t.dispatchManager(event)
+	}
+
+	tl, ok := t.toplevels[event.object]
+	if !ok {
+		// wl_seat and objects already destroyed
+		return nil
+	}
+	address := strconv.FormatUint(uint64(event.object), 10)
+
+	switch {
+	case !t.kde && event.opcode == wlrHandleAppID:
+		tl.appID = event.args.readString()
+
+	case t.kde && event.opcode == kdeWindowAppID:
+		tl.appID = event.args.readString()
+		if tl.ready {
+			t.announce(address, tl, tl.active)
+		}
+
+	case !t.kde && event.opcode == wlrHandleState:
+		states := event.args.readArray()
+		tl.pendingActive = false
+		for i := 0; i+4 <= len(states); i += 4 {
+			if binary.LittleEndian.Uint32(states[i:]) == wlrStateActivated {
+				tl.pendingActive = true
+			}
+		}
+
+	case t.kde && event.opcode == kdeWindowStateChanged:
+		wasActive := tl.active
+		tl.active = event.args.readUint()&kdeStateActive != 0
+		// KDE has no done event after the initial state
+		if tl.ready {
+			t.announce(address, tl, wasActive)
+		}
+
+	case !t.kde && event.opcode == wlrHandleDone:
+		// wlroots applies the preceding state events atomically on done
+		wasActive := tl.active
+		tl.active = tl.pendingActive
+		tl.ready = true
+		t.announce(address, tl, wasActive)
+
+	case t.kde && event.opcode == kdeWindowInitialState:
+		tl.ready = true
+		t.announce(address, tl, false)
+
+	case !t.kde && event.opcode == wlrHandleClosed,
+		t.kde && event.opcode == kdeWindowUnmapped:
+		if tl.mapped {
+			t.pending = append(t.pending, WindowEvent{Type: WindowClosed, Address: address})
+		}
+		delete(t.toplevels, event.object)
+		destroy := wlrHandleDestroy
+		if t.kde {
+			destroy = kdeWindowDestroy
+		}
+		return t.conn.send(event.object, destroy, nil)
+	}
+	return event.args.err
+}
+
+// dispatchManager handles new toplevels announced by the manager.
+func (t *Toplevel) dispatchManager(event waylandEvent) error {
+	switch {
+	case !t.kde && event.opcode == wlrManagerToplevel:
+		id := event.args.readUint()
+		if event.args.err != nil {
+			return event.args.err
+		}
+		t.toplevels[id] = &toplevel{}
+
+	case !t.kde && event.opcode == wlrManagerFinished:
+		return fmt.Errorf("compositor stopped sending toplevels")
+
+	case t.kde && (event.opcode == kdeManagerWindow || event.opcode == kdeManagerWindowWithUUID):
+		internalID := event.args.readUint()
+		if event.args.err != nil {
+			return event.args.err
+		}
+		if t.kdeIDs[internalID] {
+			return nil
+		}
+		t.kdeIDs[internalID] = true
+
+		id := t.conn.newID()
+		// Before version 4 there is no initial_state event
+		t.toplevels[id] = &toplevel{ready: t.version < 4}
+		return t.conn.send(t.manager, kdeManagerGetWindow, newWaylandMessage().appendUint(id).appendUint(internalID))
+	}
+	return nil
+}
+
+// announce queues the opened event of a toplevel once its app id is known,
+// and a focused event when it became active.
+func (t *Toplevel) announce(address string, tl *toplevel, wasActive bool) {
+	if !tl.mapped && tl.appID != "" {
+		tl.mapped = true
+		t.pending = append(t.pending, WindowEvent{Type: WindowOpened, Address: address, Class: tl.appID})
+	}
+	if tl.mapped && tl.active && !wasActive {
+		t.pending = append(t.pending, WindowEvent{Type: WindowFocused, Address: address})
+	}
+}
+
+func (t *Toplevel) FindWindow(classNames []string) (Window, error) {
+	log := global.GetLogger()
+	notifier := global.GetNotifier()
+
+	t.mu.Lock()
+	if t.err != nil {
+		err := t.err
+		t.mu.Unlock()
+		return Window{}, fmt.Errorf("%s toplevel connection lost: %w", t.Name(), err)
+	}
+
+	var foundWindow Window
+	for id, tl := range t.toplevels {
+		if !tl.mapped {
+			continue
+		}
+		appID := strings.ToLower(tl.appID)
+		for _, className := range classNames {
+			if strings.Contains(appID, strings.ToLower(className)) {
+				foundWindow = Window{
+					Class:   tl.appID,
+					Address: strconv.FormatUint(uint64(id), 10),
+				}
+				break
+			}
+		}
+		if !foundWindow.IsEmpty() {
+			break
+		}
+	}
+	t.mu.Unlock()
+
+	if !foundWindow.IsEmpty() {
+		// Only log if this is a different window than last time
+		if foundWindow != t.lastFoundWindow {
+			log.Debug("Found matching window by app_id",
+				"app_id", foundWindow.Class,
+				"address", foundWindow.Address)
+			t.lastFoundWindow = foundWindow
+		}
+
+		t.hasLoggedWaiting = false
+		return foundWindow, nil
+	}
+
+	// Reset last found window when no window is found
+	if t.lastFoundWindow != (Window{}) {
+		t.lastFoundWindow = Window{}
+	}
+
+	if !t.hasLoggedWaiting {
+		var message = "Waiting for PoE Window..."
+		log.Info(message)
+		notifier.Show(message, notify.Info)
+		t.hasLoggedWaiting = true
+	}
+
+	return Window{}, nil
+}
+
+func (t *Toplevel) FocusWindow(w Window) error {
+	log := global.GetLogger()
+
+	log.Debug("Focusing window", "address", w.Address)
+
+	id, err := strconv.ParseUint(w.Address, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid toplevel address %q: %w", w.Address, err)
+	}
+
+	t.mu.Lock()
+	_, ok := t.toplevels[uint32(id)]
+	t.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("window %s is gone", w.Address)
+	}
+
+	if t.kde {
+		err = t.conn.send(uint32(id), kdeWindowSetState, newWaylandMessage().appendUint(kdeStateActive).appendUint(kdeStateActive))
+	} else {
+		err = t.conn.send(uint32(id), wlrHandleActivate, newWaylandMessage().appendUint(t.seat))
+	}
+	if err != nil {
+		log.Error("Failed to focus window", err)
+		return fmt.Errorf("failed to focus window: %w", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	return nil
+}
+
+// WatchEvents reports the events of the reader goroutine until stop is
+// closed or the connection fails.
+func (t *Toplevel) WatchEvents(stop <-chan struct{}, handle func(WindowEvent)) error {
+	t.mu.Lock()
+	if t.err != nil {
+		err := t.err
+		t.mu.Unlock()
+		return fmt.Errorf("%s toplevel connection lost: %w", t.Name(), err)
+	}
+	if t.handler != nil {
+		t.mu.Unlock()
+		return fmt.Errorf("%s toplevel events are already watched", t.Name())
+	}
+	t.handler = handle
+	t.mu.Unlock()
+
+	global.GetLogger().Info("Listening to window events", "wm", t.Name())
+
+	defer func() {
+		t.mu.Lock()
+		t.handler = nil
+		t.mu.Unlock()
+	}()
+
+	select {
+	case <-stop:
+		return nil
+	case <-t.broken:
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return fmt.Errorf("%s toplevel connection lost: %w", t.Name(), t.err)
+	}
+}
@@ -0,0 +1,299 @@
+package wm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// waylandTimeout bounds connecting to the compositor and the initial
+// roundtrips
+const waylandTimeout = 2 * time.Second
+
+// wl_display is always object 1
+const (
+	waylandDisplay uint32 = 1
+
+	// wl_display requests
+	waylandDisplaySync        uint16 = 0
+	waylandDisplayGetRegistry uint16 = 1
+
+	// wl_display events
+	waylandDisplayError uint16 = 0
+
+	// wl_registry request bind and event global
+	waylandRegistryBind   uint16 = 0
+	waylandRegistryGlobal uint16 = 0
+
+	// wl_callback event done
+	waylandCallbackDone uint16 = 0
+)
+
+// waylandConn is a minimal Wayland client: enough of the wire protocol to
+// bind globals, send requests and read events. File descriptors are neither
+// sent nor received, so only protocols without fd arguments can be used.
+type waylandConn struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	registry uint32
+	globals  map[string]waylandGlobal // by interface name
+
+	mu     sync.Mutex // guards writes and nextID
+	nextID uint32
+}
+
+// waylandGlobal is a global object advertised by the compositor
+type waylandGlobal struct {
+	name    uint32
+	version uint32
+}
+
+// waylandEvent is an event read from the connection, its arguments still
+// encoded
+type waylandEvent struct {
+	object uint32
+	opcode uint16
+	args   waylandArgs
+}
+
+// waylandSocketPath returns the socket of the compositor: $WAYLAND_DISPLAY,
+// relative to $XDG_RUNTIME_DIR unless absolute, "wayland-0" by default.
+func waylandSocketPath() (string, error) {
+	display := os.Getenv("WAYLAND_DISPLAY")
+	if display == "" {
+		display = "wayland-0"
+	}
+	if filepath.IsAbs(display) {
+		return display, nil
+	}
+
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		return "", fmt.Errorf("XDG_RUNTIME_DIR is not set")
+	}
+	return filepath.Join(runtimeDir, display), nil
+}
+
+// dialWayland connects to the compositor and lists its globals.
+func dialWayland() (*waylandConn, error) {
+	path, err := waylandSocketPath()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("unix", path, waylandTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Wayland display %s: %w", path, err)
+	}
+
+	c := &waylandConn{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		globals: make(map[string]waylandGlobal),
+		nextID:  waylandDisplay + 1,
+	}
+
+	c.registry = c.newID()
+	if err := c.send(waylandDisplay, waylandDisplayGetRegistry, newWaylandMessage().appendUint(c.registry)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	err = c.roundtrip(func(event waylandEvent) error {
+		if event.object != c.registry || event.opcode != waylandRegistryGlobal {
+			return nil
+		}
+		name := event.args.readUint()
+		iface := event.args.readString()
+		version := event.args.readUint()
+		if event.args.err != nil {
+			return event.args.err
+		}
+		c.globals[iface] = waylandGlobal{name: name, version: version}
+		return nil
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close closes the connection, which destroys all its objects.
+func (c *waylandConn) Close() error {
+	return c.conn.Close()
+}
+
+// newID allocates a client object id.
+func (c *waylandConn) newID() uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id := c.nextID
+	c.nextID++
+	return id
+}
+
+// bind creates an object for a global at the lower of version and the
+// version the compositor advertises, and returns its id and version.
+func (c *waylandConn) bind(iface string, version uint32) (uint32, uint32, error) {
+	global, ok := c.globals[iface]
+	if !ok {
+		return 0, 0, fmt.Errorf("compositor does not advertise %s", iface)
+	}
+	version = min(version, global.version)
+
+	id := c.newID()
+	msg := newWaylandMessage().appendUint(global.name).appendString(iface).appendUint(version).appendUint(id)
+	if err := c.send(c.registry, waylandRegistryBind, msg); err != nil {
+		return 0, 0, err
+	}
+	return id, version, nil
+}
+
+// send writes a request: object id, message size and opcode, arguments.
+func (c *waylandConn) send(object uint32, opcode uint16, args waylandMessage) error {
+	msg := make([]byte, 8, 8+len(args))
+	binary.LittleEndian.PutUint32(msg, object)
+	binary.LittleEndian.PutUint32(msg[4:], uint32(len(args)+8)<<16|uint32(opcode))
+	msg = append(msg, args...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.conn.Write(msg); err != nil {
+		return fmt.Errorf("failed to send Wayland request: %w", err)
+	}
+	return nil
+}
+
+// readEvent reads the next event. Protocol errors reported by the compositor
+// are returned as errors.
+func (c *waylandConn) readEvent() (waylandEvent, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(c.reader, header); err != nil {
+		return waylandEvent{}, fmt.Errorf("failed to read Wayland event: %w", err)
+	}
+
+	object := binary.LittleEndian.Uint32(header)
+	sizeOpcode := binary.LittleEndian.Uint32(header[4:])
+	size := int(sizeOpcode >> 16)
+	if size < 8 {
+		return waylandEvent{}, fmt.Errorf("invalid Wayland event size %d", size)
+	}
+
+	args := make([]byte, size-8)
+	if _, err := io.ReadFull(c.reader, args); err != nil {
+		return waylandEvent{}, fmt.Errorf("failed to read Wayland event: %w", err)
+	}
+
+	event := waylandEvent{object: object, opcode: uint16(sizeOpcode), args: waylandArgs{data: args}}
+	if object == waylandDisplay && event.opcode == waylandDisplayError {
+		failed := event.args.readUint()
+		code := event.args.readUint()
+		message := event.args.readString()
+		return waylandEvent{}, fmt.Errorf("wayland protocol error on object %d (code %d): %s", failed, code, message)
+	}
+	return event, nil
+}
+
+// roundtrip passes events to handle until the compositor processed all
+// requests sent so far. It must not run concurrently with another reader.
+func (c *waylandConn) roundtrip(handle func(waylandEvent) error) error {
+	callback := c.newID()
+	if err := c.send(waylandDisplay, waylandDisplaySync, newWaylandMessage().appendUint(callback)); err != nil {
+		return err
+	}
+
+	if err := c.conn.SetReadDeadline(time.Now().Add(waylandTimeout)); err != nil {
+		return fmt.Errorf("failed to set Wayland read deadline: %w", err)
+	}
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	for {
+		event, err := c.readEvent()
+		if err != nil {
+			return err
+		}
+		if event.object == callback && event.opcode == waylandCallbackDone {
+			return nil
+		}
+		if err := handle(event); err != nil {
+			return err
+		}
+	}
+}
+
+// waylandMessage builds the arguments of a request
+type waylandMessage []byte
+
+func newWaylandMessage() waylandMessage {
+	return waylandMessage{}
+}
+
+// appendUint appends a uint, int, object or new_id argument.
+func (m waylandMessage) appendUint(v uint32) waylandMessage {
+	return binary.LittleEndian.AppendUint32(m, v)
+}
+
+// appendString appends a string argument: length including the terminating NUL,
+// then the bytes padded to 32 bits.
+func (m waylandMessage) appendString(s string) waylandMessage {
+	m = m.appendUint(uint32(len(s) + 1))
+	m = append(m, s...)
+	m = append(m, 0)
+	for len(m)%4 != 0 {
+		m = append(m, 0)
+	}
+	return m
+}
+
+// waylandArgs decodes the arguments of an event in order. The first decoding
+// error is kept in err and makes the following reads return zero values.
+type waylandArgs struct {
+	data []byte
+	err  error
+}
+
+// readUint reads a uint, int, object or new_id argument.
+func (a *waylandArgs) readUint() uint32 {
+	if a.err != nil {
+		return 0
+	}
+	if len(a.data) < 4 {
+		a.err = fmt.Errorf("truncated Wayland event")
+		return 0
+	}
+	v := binary.LittleEndian.Uint32(a.data)
+	a.data = a.data[4:]
+	return v
+}
+
+// readArray reads an array argument.
+func (a *waylandArgs) readArray() []byte {
+	length := int(a.readUint())
+	if a.err != nil {
+		return nil
+	}
+	padded := (length + 3) &^ 3
+	if len(a.data) < padded {
+		a.err = fmt.Errorf("truncated Wayland event")
+		return nil
+	}
+	v := a.data[:length]
+	a.data = a.data[padded:]
+	return v
+}
+
+// readString reads a string argument, dropping the terminating NUL.
+func (a *waylandArgs) readString() string {
+	v := a.readArray()
+	if len(v) == 0 {
+		return ""
+	}
+	return string(v[:len(v)-1])
+}
@@ -2,72 +2,187 @@ package wm
 
 import (
 	"fmt"
-	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+
 	"hypr-exiled/pkg/global"
 	"hypr-exiled/pkg/notify"
 )
 
+// X11 finds and focuses windows over an in-process X11 connection, through
+// the EWMH properties the window manager maintains on the root window.
+// Window managers without EWMH support fall back to xdotool.
 type X11 struct {
+	conn  *xgb.Conn
+	root  xproto.Window
+	atoms x11Atoms
+
 	hasLoggedWaiting bool
 	lastFoundWindow  Window
 }
 
+// x11Atoms are the atoms used by the backend. Atoms are global to the X
+// server, so they are valid on every connection.
+type x11Atoms struct {
+	supported    xproto.Atom // _NET_SUPPORTED
+	clientList   xproto.Atom // _NET_CLIENT_LIST
+	activeWindow xproto.Atom // _NET_ACTIVE_WINDOW
+}
+
+// NewX11 connects to the X server of $DISPLAY. Without EWMH support it
+// returns the xdotool backend when xdotool is installed.
 func NewX11() (WindowManager, error) {
 	log := global.GetLogger()
 
-	if _, err := exec.LookPath("xdotool"); err != nil {
-		log.Error("xdotool not found in PATH", err)
-		return nil, fmt.Errorf("xdotool is required for X11 support: %w", err)
+	x, err := newEWMH()
+	if err == nil {
+		return x, nil
 	}
+	log.Warn("EWMH window management not available, trying xdotool", "error", err)
 
-	return &X11{}, nil
+	xdotool, xdotoolErr := NewXdotool()
+	if xdotoolErr != nil {
+		return nil, fmt.Errorf("%w; %w", err, xdotoolErr)
+	}
+	return xdotool, nil
+}
+
+// newEWMH connects to the X server and checks that the window manager
+// publishes its client list.
+func newEWMH() (*X11, error) {
+	log := global.GetLogger()
+
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X server: %w", err)
+	}
+
+	x := &X11{
+		conn: conn,
+		root: xproto.Setup(conn).DefaultScreen(conn).Root,
+	}
+
+	for name, atom := range map[string]*xproto.Atom{
+		"_NET_SUPPORTED":     &x.atoms.supported,
+		"_NET_CLIENT_LIST":   &x.atoms.clientList,
+		"_NET_ACTIVE_WINDOW": &x.atoms.activeWindow,
+	} {
+		reply, err := xproto.InternAtom(conn, false, uint16(len(name)), name).Reply()
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to intern atom %s: %w", name, err)
+		}
+		*atom = reply.Atom
+	}
+
+	supported, err := x11Property32(conn, x.root, x.atoms.supported)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read _NET_SUPPORTED: %w", err)
+	}
+	var hasClientList, hasActiveWindow bool
+	for _, atom := range supported {
+		hasClientList = hasClientList || xproto.Atom(atom) == x.atoms.clientList
+		hasActiveWindow = hasActiveWindow || xproto.Atom(atom) == x.atoms.activeWindow
+	}
+	if !hasClientList || !hasActiveWindow {
+		conn.Close()
+		return nil, fmt.Errorf("window manager does not support _NET_CLIENT_LIST and _NET_ACTIVE_WINDOW")
+	}
+
+	log.Debug("Connected to X server", "root", x.root)
+	return x, nil
 }
 
 func (x *X11) Name() string {
 	return "X11"
 }
 
+// x11Property32 reads a property holding a list of 32-bit values, such as
+// windows or atoms.
+func x11Property32(conn *xgb.Conn, win xproto.Window, property xproto.Atom) ([]uint32, error) {
+	reply, err := xproto.GetProperty(conn, false, win, property, xproto.GetPropertyTypeAny, 0, 1<<16).Reply()
+	if err != nil {
+		return nil, err
+	}
+	if reply.Format != 32 {
+		return nil, nil
+	}
+
+	values := make([]uint32, 0, reply.ValueLen)
+	for i := 0; i+4 <= len(reply.Value); i += 4 {
+		values = append(values, xgb.Get32(reply.Value[i:]))
+	}
+	return values, nil
+}
+
+// x11Class returns the instance and class names of WM_CLASS.
+func x11Class(conn *xgb.Conn, win xproto.Window) (string, string, error) {
+	reply, err := xproto.GetProperty(conn, false, win, xproto.AtomWmClass, xproto.AtomString, 0, 256).Reply()
+	if err != nil {
+		return "", "", err
+	}
+
+	// Two NUL terminated strings: instance, then class
+	parts := strings.Split(strings.TrimRight(string(reply.Value), "\x00"), "\x00")
+	if len(parts) < 2 {
+		return parts[0], parts[0], nil
+	}
+	return parts[0], parts[1], nil
+}
+
+// x11Matches reports whether the instance or class name contains one of the
+// class names, ignoring case.
+func x11Matches(instance, class string, classNames []string) bool {
+	instance, class = strings.ToLower(instance), strings.ToLower(class)
+	for _, name := range classNames {
+		name = strings.ToLower(name)
+		if name != "" && (strings.Contains(class, name) || strings.Contains(instance, name)) {
+			return true
+		}
+	}
+	return false
+}
+
 func (x *X11) FindWindow(classNames []string) (Window, error) {
 	log := global.GetLogger()
 	notifier := global.GetNotifier()
 
-	for _, class := range classNames {
-		out, err := exec.Command("xdotool", "search", "--class", class).CombinedOutput()
+	clients, err := x11Property32(x.conn, x.root, x.atoms.clientList)
+	if err != nil {
+		log.Error("Failed to read client list", err)
+		return Window{}, fmt.Errorf("failed to read _NET_CLIENT_LIST: %w", err)
+	}
+
+	for _, client := range clients {
+		instance, class, err := x11Class(x.conn, xproto.Window(client))
 		if err != nil {
-			log.Debug("xdotool search failed", "class", class, "error", err)
+			// Windows can close between the list and the query
+			log.Debug("Failed to get window class", "window", client, "error", err)
+			continue
+		}
+		if !x11Matches(instance, class, classNames) {
 			continue
 		}
 
-		windowIDs := strings.Split(strings.TrimSpace(string(out)), "\n")
-		for _, windowID := range windowIDs {
-			if windowID == "" {
-				continue
-			}
-
-			classNameOut, err := exec.Command("xdotool", "getwindowclassname", windowID).CombinedOutput()
-			if err != nil {
-				log.Debug("Failed to get window class", "windowID", windowID, "error", err)
-				continue
-			}
-
-			foundWindow := Window{
-				Address: windowID,
-				Class:   strings.TrimSpace(string(classNameOut)),
-			}
-
-			if foundWindow != x.lastFoundWindow {
-				log.Debug("Found matching window",
-					"class", foundWindow.Class,
-					"address", foundWindow.Address)
-				x.lastFoundWindow = foundWindow
-			}
+		foundWindow := Window{
+			Address: strconv.FormatUint(uint64(client), 10),
+			Class:   class,
+		}
 
-			x.hasLoggedWaiting = false
-			return foundWindow, nil
+		if foundWindow != x.lastFoundWindow {
+			log.Debug("Found matching window",
+				"class", foundWindow.Class,
+				"address", foundWindow.Address)
+			x.lastFoundWindow = foundWindow
 		}
+
+		x.hasLoggedWaiting = false
+		return foundWindow, nil
 	}
 
 	if x.lastFoundWindow != (Window{}) {
@@ -84,20 +199,133 @@ func (x *X11) FindWindow(classNames []string) (Window, error) {
 	return Window{}, nil
 }
 
+// FocusWindow asks the window manager to activate the window with a
+// _NET_ACTIVE_WINDOW client message, as pagers do.
 func (x *X11) FocusWindow(w Window) error {
 	log := global.GetLogger()
 
 	log.Debug("Focusing X11 window", "address", w.Address, "class", w.Class)
 
-	cmd := exec.Command("xdotool", "windowactivate", w.Address)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		log.Error("Failed to focus window",
-			err,
-			"output", string(output),
-			"address", w.Address)
+	id, err := strconv.ParseUint(w.Address, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid X11 window %q: %w", w.Address, err)
+	}
+
+	event := xproto.ClientMessageEvent{
+		Format: 32,
+		Window: xproto.Window(id),
+		Type:   x.atoms.activeWindow,
+		// Source indication 2 (pager), current time, no active window
+		Data: xproto.ClientMessageDataUnionData32New([]uint32{2, xproto.TimeCurrentTime, 0, 0, 0}),
+	}
+	mask := uint32(xproto.EventMaskSubstructureRedirect | xproto.EventMaskSubstructureNotify)
+	if err := xproto.SendEventChecked(x.conn, false, x.root, mask, string(event.Bytes())).Check(); err != nil {
+		log.Error("Failed to focus window", err, "address", w.Address)
 		return fmt.Errorf("failed to focus window: %w", err)
 	}
 
 	time.Sleep(100 * time.Millisecond)
 	return nil
 }
+
+// WatchEvents follows PropertyNotify events of the root window on a
+// dedicated connection: changes of _NET_CLIENT_LIST report opened and closed
+// windows, changes of _NET_ACTIVE_WINDOW focused ones.
+func (x *X11) WatchEvents(stop <-chan struct{}, handle func(WindowEvent)) error {
+	log := global.GetLogger()
+
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return fmt.Errorf("failed to connect to X server: %w", err)
+	}
+	defer conn.Close()
+
+	// Unblock WaitForEvent when asked to stop
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	err = xproto.ChangeWindowAttributesChecked(conn, x.root, xproto.CwEventMask,
+		[]uint32{xproto.EventMaskPropertyChange}).Check()
+	if err != nil {
+		return fmt.Errorf("failed to watch root window: %w", err)
+	}
+
+	clients, err := x11Property32(conn, x.root, x.atoms.clientList)
+	if err != nil {
+		return fmt.Errorf("failed to read _NET_CLIENT_LIST: %w", err)
+	}
+	known := make(map[uint32]bool, len(clients))
+	for _, client := range clients {
+		known[client] = true
+	}
+
+	log.Info("Listening to window events", "wm", x.Name())
+
+	for {
+		event, xerr := conn.WaitForEvent()
+		if event == nil && xerr == nil {
+			select {
+			case <-stop:
+				return nil
+			default:
+			}
+			return fmt.Errorf("X server connection closed")
+		}
+		if xerr != nil {
+			// Windows can close before their properties are read
+			log.Debug("X11 error while watching events", "error", xerr)
+			continue
+		}
+
+		property, ok := event.(xproto.PropertyNotifyEvent)
+		if !ok || property.Window != x.root {
+			continue
+		}
+
+		switch property.Atom {
+		case x.atoms.clientList:
+			clients, err := x11Property32(conn, x.root, x.atoms.clientList)
+			if err != nil {
+				return fmt.Errorf("failed to read _NET_CLIENT_LIST: %w", err)
+			}
+
+			current := make(map[uint32]bool, len(clients))
+			for _, client := range clients {
+				current[client] = true
+				if known[client] {
+					continue
+				}
+				_, class, err := x11Class(conn, xproto.Window(client))
+				if err != nil {
+					continue
+				}
+				handle(WindowEvent{Type: WindowOpened, Address: strconv.FormatUint(uint64(client), 10), Class: class})
+			}
+			for client := range known {
+				if !current[client] {
+					handle(WindowEvent{Type: WindowClosed, Address: strconv.FormatUint(uint64(client), 10)})
+				}
+			}
+			known = current
+
+		case x.atoms.activeWindow:
+			active, err := x11Property32(conn, x.root, x.atoms.activeWindow)
+			if err != nil {
+				continue
+			}
+			// Empty or 0 when no window has focus, e.g. the desktop
+			address := ""
+			if len(active) > 0 && active[0] != 0 {
+				address = strconv.FormatUint(uint64(active[0]), 10)
+			}
+			handle(WindowEvent{Type: WindowFocused, Address: address})
+		}
+	}
+}
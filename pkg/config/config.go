@@ -1,7 +1,7 @@
 package config
 
 import (
-	"regexp"
+	"time"
 
 	"hypr-exiled/pkg/logger"
 )
@@ -13,9 +13,15 @@ type Config struct {
 	triggers      map[string]string
 	commands      map[string][]string
 	notifyCommand string
+	logWatchMode  string
+	arrivalSound  bool
+	retention     Retention
+	duplicates    Duplicates
+	sort          Sort
+	windowManager string
 
 	// Internal fields
-	compiledTriggers map[string]*regexp.Regexp `json:"-"`
+	compiledTriggers map[string]*Trigger `json:"-"`
 	log              *logger.Logger
 	assetsDir        string `json:"-"`
 
@@ -37,3 +43,94 @@ func New(log *logger.Logger) *Config {
 func (c *Config) GetPoeLogPath() string {
 	return c.poeLogPath
 }
+
+// GetLogWatchMode returns how Client.txt is tailed: "auto" (inotify with
+// polling fallback), "inotify" or "poll".
+func (c *Config) GetLogWatchMode() string {
+	if c.logWatchMode == "" {
+		return "auto"
+	}
+	return c.logWatchMode
+}
+
+// Retention controls how long trades are kept. A zero duration keeps them
+// forever.
+type Retention struct {
+	OpenTrades time.Duration // open trades are closed as expired after this
+	History    time.Duration // closed trades are deleted from the history after this
+	Unanswered time.Duration // incoming trades still new after this are closed as expired
+}
+
+// defaultOpenTradeRetention is the retention of open trades when the config
+// does not set one.
+const defaultOpenTradeRetention = 24 * time.Hour
+
+// GetRetention returns the retention policy for trades and history.
+func (c *Config) GetRetention() Retention {
+	return c.retention
+}
+
+// GetArrivalSound reports whether a sound plays when a buyer joins the area.
+func (c *Config) GetArrivalSound() bool {
+	return c.arrivalSound
+}
+
+// Duplicates controls how repeated whispers are folded into the open trade
+// they repeat instead of being added again.
+type Duplicates struct {
+	Window time.Duration // a repeat within this time of the last whisper is a ping; zero disables
+	Silent bool          // no trade sound for pings
+}
+
+// defaultDuplicateWindow is the duplicate window when the config does not
+// set one.
+const defaultDuplicateWindow = 10 * time.Minute
+
+// GetDuplicates returns how repeated whispers are detected.
+func (c *Config) GetDuplicates() Duplicates {
+	return c.duplicates
+}
+
+// Sort controls the order of the trades UI.
+type Sort struct {
+	Mode  string             // initial sort mode, see SortModes
+	Rates map[string]float64 // value of one unit of a currency in the reference currency
+}
+
+// SortModes are the orders the trades UI can be switched between, in the
+// order the sort key cycles through them.
+var SortModes = []string{"arrived", "newest", "oldest", "value", "player"}
+
+// defaultCurrencyRates rank currencies when the config sets no rates, with
+// divine as reference currency. Only the order matters for sorting, so
+// rough values are enough.
+var defaultCurrencyRates = map[string]float64{
+	"divine":  1,
+	"exalted": 0.01,
+	"chaos":   0.02,
+}
+
+// GetSort returns the sort mode and currency rates of the trades UI.
+func (c *Config) GetSort() Sort {
+	sort := c.sort
+	if sort.Mode == "" {
+		sort.Mode = SortModes[0]
+	}
+	if len(sort.Rates) == 0 {
+		sort.Rates = defaultCurrencyRates
+	}
+	return sort
+}
+
+// WindowManagers are the window manager backends the window_manager config
+// can force. "auto" picks one from the session.
+var WindowManagers = []string{"auto", "hyprland", "sway", "i3", "toplevel", "x11", "xdotool"}
+
+// GetWindowManager returns the window manager backend, "auto" unless the
+// config forces one.
+func (c *Config) GetWindowManager() string {
+	if c.windowManager == "" {
+		return WindowManagers[0]
+	}
+	return c.windowManager
+}
@@ -20,16 +20,16 @@ func DefaultConfig(log *logger.Logger) (*Config, error) {
 
 	config := &Config{
 		poeLogPath: logPath,
-		triggers: map[string]string{
-			"incoming_trade": `\[INFO Client \d+\] @From ([^:]+): Hi, I would like to buy your ([^,]+(?:,[^,]+)*) listed for (\d+(?:\.\d+)?) ([^ ]+) in ([^\(]+) \(stash tab "([^"]+)"; position: left (\d+), top (\d+)\)`,
-			"outgoing_trade": `\[INFO Client \d+\] @To ([^:]+): Hi, I would like to buy your ([^,]+(?:,[^,]+)*) listed for (\d+(?:\.\d+)?) ([^ ]+) in ([^\(]+) \(stash tab "([^"]+)"; position: left (\d+), top (\d+)\)`,
-		},
+		triggers:   defaultTriggers(),
 		commands: map[string][]string{
-			"party":  {"/invite {player}"},
-			"finish": {"/kick {player}", "@{player} thanks!"},
-			"trade":  {"/tradewith {player}"},
+			"party":      {"/invite {player}"},
+			"finish":     {"/kick {player}", "@{player} thanks!"},
+			"trade":      {"/tradewith {player}"},
+			"auto_reply": {"@{player} busy in a map, will invite in ~5 min"},
 		},
 		notifyCommand: "",
+		retention:     Retention{OpenTrades: defaultOpenTradeRetention},
+		duplicates:    Duplicates{Window: defaultDuplicateWindow},
 		log:           log,
 	}
 
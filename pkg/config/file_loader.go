@@ -2,7 +2,12 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"hypr-exiled/pkg/logger"
 )
@@ -24,6 +29,22 @@ func (c *Config) LoadFromFile(path string, log *logger.Logger) error {
 		Triggers      map[string]string   `json:"triggers"`
 		Commands      map[string][]string `json:"commands"`
 		NotifyCommand string              `json:"notify_command"`
+		LogWatchMode  string              `json:"log_watch_mode"`
+		ArrivalSound  bool                `json:"arrival_sound"`
+		Retention     struct {
+			OpenTrades string `json:"open_trades"`
+			History    string `json:"history"`
+			Unanswered string `json:"unanswered"`
+		} `json:"retention"`
+		Duplicates struct {
+			Window string `json:"window"`
+			Silent bool   `json:"silent"`
+		} `json:"duplicates"`
+		Sort struct {
+			Mode  string             `json:"mode"`
+			Rates map[string]float64 `json:"rates"`
+		} `json:"sort"`
+		WindowManager string `json:"window_manager"`
 	}
 	if err := json.Unmarshal(data, &temp); err != nil {
 		log.Error("Failed to parse config JSON", err)
@@ -36,10 +57,85 @@ func (c *Config) LoadFromFile(path string, log *logger.Logger) error {
 	c.triggers = temp.Triggers
 	c.commands = temp.Commands
 	c.notifyCommand = temp.NotifyCommand
+	c.logWatchMode = temp.LogWatchMode
+	c.arrivalSound = temp.ArrivalSound
+
+	switch c.logWatchMode {
+	case "", "auto", "inotify", "poll":
+	default:
+		log.Error("Invalid log_watch_mode", nil, "value", c.logWatchMode)
+		return fmt.Errorf("invalid log_watch_mode %q: expected auto, inotify or poll", c.logWatchMode)
+	}
+
+	c.retention.OpenTrades = defaultOpenTradeRetention
+	if temp.Retention.OpenTrades != "" {
+		if c.retention.OpenTrades, err = parseRetention(temp.Retention.OpenTrades); err != nil {
+			log.Error("Invalid retention.open_trades", err, "value", temp.Retention.OpenTrades)
+			return fmt.Errorf("invalid retention.open_trades: %w", err)
+		}
+	}
+	if temp.Retention.History != "" {
+		if c.retention.History, err = parseRetention(temp.Retention.History); err != nil {
+			log.Error("Invalid retention.history", err, "value", temp.Retention.History)
+			return fmt.Errorf("invalid retention.history: %w", err)
+		}
+	}
+	if temp.Retention.Unanswered != "" {
+		if c.retention.Unanswered, err = parseRetention(temp.Retention.Unanswered); err != nil {
+			log.Error("Invalid retention.unanswered", err, "value", temp.Retention.Unanswered)
+			return fmt.Errorf("invalid retention.unanswered: %w", err)
+		}
+	}
+
+	c.duplicates = Duplicates{Window: defaultDuplicateWindow, Silent: temp.Duplicates.Silent}
+	if temp.Duplicates.Window != "" {
+		if c.duplicates.Window, err = time.ParseDuration(temp.Duplicates.Window); err != nil || c.duplicates.Window < 0 {
+			log.Error("Invalid duplicates.window", err, "value", temp.Duplicates.Window)
+			return fmt.Errorf("invalid duplicates.window %q: expected a duration like \"10m\" or \"0\"", temp.Duplicates.Window)
+		}
+	}
+
+	c.sort = Sort{Mode: temp.Sort.Mode, Rates: temp.Sort.Rates}
+	if c.sort.Mode != "" && !slices.Contains(SortModes, c.sort.Mode) {
+		log.Error("Invalid sort.mode", nil, "value", c.sort.Mode)
+		return fmt.Errorf("invalid sort.mode %q: expected one of %s", c.sort.Mode, strings.Join(SortModes, ", "))
+	}
+
+	c.windowManager = temp.WindowManager
+	if c.windowManager != "" && !slices.Contains(WindowManagers, c.windowManager) {
+		log.Error("Invalid window_manager", nil, "value", c.windowManager)
+		return fmt.Errorf("invalid window_manager %q: expected one of %s", c.windowManager, strings.Join(WindowManagers, ", "))
+	}
 
 	return c.compile()
 }
 
+// parseRetention parses a Go duration ("36h") or a number of days ("90d").
+// "0" and "forever" keep everything.
+func parseRetention(value string) (time.Duration, error) {
+	switch value {
+	case "0", "forever":
+		return 0, nil
+	}
+
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid number of days %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative duration %q", value)
+	}
+	return d, nil
+}
+
 // loadConfigFromPath loads the configuration from a file.
 func loadConfigFromPath(path string, log *logger.Logger) (*Config, error) {
 	config := &Config{log: log}
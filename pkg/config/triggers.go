@@ -1,24 +1,170 @@
 package config
 
 import (
+	"fmt"
 	"regexp"
+	"sort"
 )
 
+// Trade fields a trigger can capture with named groups, e.g. `(?P<player>[^:]+)`.
+const (
+	FieldPlayer     = "player"
+	FieldItem       = "item"
+	FieldItemAmount = "item_amount"
+	FieldAmount     = "amount"
+	FieldCurrency   = "currency"
+	FieldLeague     = "league"
+	FieldStash      = "stash"
+	FieldLeft       = "left"
+	FieldTop        = "top"
+	FieldLevel      = "level"
+	FieldQuality    = "quality"
+	FieldNote       = "note"
+)
+
+// knownTriggerFields lists every named group the log parser understands.
+var knownTriggerFields = map[string]bool{
+	FieldPlayer:     true,
+	FieldItem:       true,
+	FieldItemAmount: true,
+	FieldAmount:     true,
+	FieldCurrency:   true,
+	FieldLeague:     true,
+	FieldStash:      true,
+	FieldLeft:       true,
+	FieldTop:        true,
+	FieldLevel:      true,
+	FieldQuality:    true,
+	FieldNote:       true,
+}
+
+// legacyTriggerFields is the fixed group order of patterns written before
+// named groups were supported.
+var legacyTriggerFields = []string{
+	FieldPlayer, FieldItem, FieldAmount, FieldCurrency,
+	FieldLeague, FieldStash, FieldLeft, FieldTop,
+}
+
+// builtinTriggers are always available; a trigger of the same name in the
+// config file replaces the built-in pattern. Listing whispers may come
+// without a stash tab (website "Whisper" button), with gem level/quality in
+// front of the item and with a free-text note appended by the buyer.
+var builtinTriggers = map[string]string{
+	"incoming_trade":      `\[INFO Client \d+\] @From (?P<player>[^:]+): Hi, I would like to buy your (?:level (?P<level>\d+) (?P<quality>\d+)% (?:Quality )?)?(?P<item>.+?) listed for (?P<amount>\d+(?:\.\d+)?) (?P<currency>[^ ]+) in (?P<league>[^\(\.]+)(?:\(stash tab "(?P<stash>[^"]+)"; position: left (?P<left>\d+), top (?P<top>\d+)\))?\.?\s*(?P<note>.*)$`,
+	"outgoing_trade":      `\[INFO Client \d+\] @To (?P<player>[^:]+): Hi, I would like to buy your (?:level (?P<level>\d+) (?P<quality>\d+)% (?:Quality )?)?(?P<item>.+?) listed for (?P<amount>\d+(?:\.\d+)?) (?P<currency>[^ ]+) in (?P<league>[^\(\.]+)(?:\(stash tab "(?P<stash>[^"]+)"; position: left (?P<left>\d+), top (?P<top>\d+)\))?\.?\s*(?P<note>.*)$`,
+	"incoming_bulk_trade": `\[INFO Client \d+\] @From (?P<player>[^:]+): Hi, I(?:'d| would) like to buy your (?P<item_amount>\d+(?:\.\d+)?) (?P<item>.+?) for my (?P<amount>\d+(?:\.\d+)?) (?P<currency>.+?) in (?P<league>[^.]+)`,
+	"outgoing_bulk_trade": `\[INFO Client \d+\] @To (?P<player>[^:]+): Hi, I(?:'d| would) like to buy your (?P<item_amount>\d+(?:\.\d+)?) (?P<item>.+?) for my (?P<amount>\d+(?:\.\d+)?) (?P<currency>.+?) in (?P<league>[^.]+)`,
+}
+
+// defaultTriggers returns a copy of the built-in trigger patterns.
+func defaultTriggers() map[string]string {
+	triggers := make(map[string]string, len(builtinTriggers))
+	for name, pattern := range builtinTriggers {
+		triggers[name] = pattern
+	}
+	return triggers
+}
+
+// Trigger is a compiled trigger pattern together with the fields it captures.
+type Trigger struct {
+	Name   string
+	Regexp *regexp.Regexp
+	fields map[string]int // field name -> submatch index
+}
+
+// newTrigger compiles a pattern and works out which fields it provides.
+func newTrigger(name, pattern string) (*Trigger, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Trigger{Name: name, Regexp: re, fields: make(map[string]int)}
+	for i, group := range re.SubexpNames() {
+		if group == "" {
+			continue
+		}
+		if !knownTriggerFields[group] {
+			return nil, fmt.Errorf("trigger %q: unknown named group %q", name, group)
+		}
+		t.fields[group] = i
+	}
+
+	if len(t.fields) == 0 {
+		// Unnamed patterns keep working as long as they use the old group layout
+		if re.NumSubexp() != len(legacyTriggerFields) {
+			return nil, fmt.Errorf("trigger %q has no named groups and %d unnamed ones; use named groups like (?P<player>...)",
+				name, re.NumSubexp())
+		}
+		for i, field := range legacyTriggerFields {
+			t.fields[field] = i + 1
+		}
+	}
+
+	if !t.Provides(FieldPlayer) {
+		return nil, fmt.Errorf("trigger %q must capture the %q field", name, FieldPlayer)
+	}
+
+	return t, nil
+}
+
+// Provides reports whether the trigger captures the given field.
+func (t *Trigger) Provides(field string) bool {
+	_, ok := t.fields[field]
+	return ok
+}
+
+// Fields returns the names of all captured fields in sorted order.
+func (t *Trigger) Fields() []string {
+	fields := make([]string, 0, len(t.fields))
+	for field := range t.fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// Match returns the captured fields by name if the line matches the trigger.
+func (t *Trigger) Match(line string) (map[string]string, bool) {
+	matches := t.Regexp.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, false
+	}
+
+	values := make(map[string]string, len(t.fields))
+	for field, index := range t.fields {
+		values[field] = matches[index]
+	}
+	return values, true
+}
+
 // compile compiles the regex patterns in the triggers map.
 func (c *Config) compile() error {
 	log := c.log
+
+	// Built-in triggers fill in every name the config does not define
+	if c.triggers == nil {
+		c.triggers = make(map[string]string)
+	}
+	for name, pattern := range builtinTriggers {
+		if _, ok := c.triggers[name]; !ok {
+			c.triggers[name] = pattern
+		}
+	}
+
 	log.Debug("Compiling trigger patterns", "trigger_count", len(c.triggers))
 
-	c.compiledTriggers = make(map[string]*regexp.Regexp)
+	c.compiledTriggers = make(map[string]*Trigger)
 	for name, pattern := range c.triggers {
 		log.Debug("Compiling trigger pattern", "name", name, "pattern", pattern)
 
-		re, err := regexp.Compile(pattern)
+		trigger, err := newTrigger(name, pattern)
 		if err != nil {
 			log.Error("Failed to compile trigger pattern", err, "name", name, "pattern", pattern)
 			return err
 		}
-		c.compiledTriggers[name] = re
+		log.Debug("Trigger fields", "name", name, "fields", trigger.Fields())
+		c.compiledTriggers[name] = trigger
 	}
 
 	log.Debug("All trigger patterns compiled successfully", "compiled_count", len(c.compiledTriggers))
@@ -35,8 +181,8 @@ func (c *Config) GetTriggers() map[string]string {
 }
 
 // GetCompiledTriggers returns a copy of the compiled triggers map.
-func (c *Config) GetCompiledTriggers() map[string]*regexp.Regexp {
-	triggersCopy := make(map[string]*regexp.Regexp)
+func (c *Config) GetCompiledTriggers() map[string]*Trigger {
+	triggersCopy := make(map[string]*Trigger)
 	for k, v := range c.compiledTriggers {
 		triggersCopy[k] = v
 	}
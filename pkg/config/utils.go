@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 
 	"hypr-exiled/pkg/logger"
+	"hypr-exiled/pkg/profile"
 )
 
 // initializeConfig creates or loads the configuration.
@@ -53,17 +54,16 @@ func initializeConfig(providedPath string, defaultPath string, log *logger.Logge
 
 // FindConfig locates and initializes the configuration.
 func FindConfig(providedPath string, log *logger.Logger, embeddedAssets embed.FS) (*Config, error) {
-	log.Info("Looking for configuration", "provided_path", providedPath)
+	log.Info("Looking for configuration", "provided_path", providedPath, "profile", profile.Name())
 
-	// Get user config directory
-	homeConfigDir, err := os.UserConfigDir()
+	// Get the config directory of the active profile
+	defaultConfigDir, err := profile.ConfigDir()
 	if err != nil {
-		log.Error("Failed to get user config directory", err)
+		log.Error("Failed to get config directory", err)
 		return nil, err
 	}
 
 	// Setup default paths
-	defaultConfigDir := filepath.Join(homeConfigDir, "hypr-exiled")
 	defaultConfigPath := filepath.Join(defaultConfigDir, "config.json")
 	defaultLogsDir := filepath.Join(defaultConfigDir, "logs")
 
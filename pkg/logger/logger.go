@@ -6,15 +6,16 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
+
+	"hypr-exiled/pkg/profile"
 )
 
 const (
-	DefaultLogDir  = "~/.local/share/hypr-exiled/logs"
+	DefaultLogDir  = "~/.local/share/hypr-exiled/logs" // default profile, see profile.LogDir
 	DefaultLogFile = "debug.log"
 )
 
@@ -79,14 +80,12 @@ func WithFile(path string) Option {
 	}
 }
 
-// getDefaultLogPath returns the expanded default log path
+// getDefaultLogPath returns the default log path of the active profile
 func getDefaultLogPath() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	logDir, err := profile.LogDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
+		return "", err
 	}
-
-	logDir := strings.Replace(DefaultLogDir, "~", homeDir, 1)
 	return filepath.Join(logDir, DefaultLogFile), nil
 }
 
@@ -0,0 +1,81 @@
+package profile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+const (
+	appName     = "hypr-exiled"
+	profilesDir = "profiles"
+	socketDir   = "/tmp"
+)
+
+var (
+	current string
+	mu      sync.RWMutex
+
+	validName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// Set selects the active profile. An empty name selects the default profile,
+// which keeps the paths of installations without profiles.
+func Set(name string) error {
+	if name != "" && !validName.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '-' and '_' only", name)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	current = name
+	return nil
+}
+
+// Name returns the active profile, empty for the default profile.
+func Name() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// ConfigDir returns the directory holding config.json, the trade database and
+// the assets of the active profile.
+func ConfigDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return namespaced(filepath.Join(configDir, appName)), nil
+}
+
+// LogDir returns the directory for the debug log of the active profile.
+func LogDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(namespaced(filepath.Join(homeDir, ".local", "share", appName)), "logs"), nil
+}
+
+// SocketPath returns the IPC socket of the active profile, so the services of
+// different profiles can run side by side.
+func SocketPath() string {
+	name := Name()
+	if name == "" {
+		return filepath.Join(socketDir, appName+".sock")
+	}
+	return filepath.Join(socketDir, fmt.Sprintf("%s-%s.sock", appName, name))
+}
+
+// namespaced appends the profile directory to base unless the default
+// profile is active.
+func namespaced(base string) string {
+	name := Name()
+	if name == "" {
+		return base
+	}
+	return filepath.Join(base, profilesDir, name)
+}
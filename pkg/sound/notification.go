@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"embed"
 	"fmt"
+	"time"
 
 	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/effects"
+	"github.com/gopxl/beep/v2/generators"
 	"github.com/gopxl/beep/v2/speaker"
 	"github.com/gopxl/beep/v2/wav"
 )
@@ -55,6 +58,52 @@ func (s *SoundNotifier) PlayTradeSound() error {
 	return nil
 }
 
+// PlayArrivalSound plays a short two-tone chime, distinct from the trade
+// sound, when a buyer joins the area.
+func (s *SoundNotifier) PlayArrivalSound() error {
+	return playChime(880, 1320)
+}
+
+// PlayFriendSound plays a rising three-tone chime for whispers of
+// allowlisted players, in place of the trade sound.
+func (s *SoundNotifier) PlayFriendSound() error {
+	return playChime(660, 880, 1100)
+}
+
+// playChime plays the given frequencies as short tones, one after another.
+func playChime(frequencies ...float64) error {
+	sr := beep.SampleRate(44100)
+	tone := sr.N(120 * time.Millisecond)
+
+	var parts []beep.Streamer
+	for i, freq := range frequencies {
+		sine, err := generators.SineTone(sr, freq)
+		if err != nil {
+			return fmt.Errorf("failed to generate tone: %w", err)
+		}
+		if i > 0 {
+			parts = append(parts, generators.Silence(sr.N(40*time.Millisecond)))
+		}
+		parts = append(parts, beep.Take(tone, sine))
+	}
+
+	chime := &effects.Gain{
+		Streamer: beep.Seq(parts...),
+		Gain:     -0.7,
+	}
+
+	// Channel to wait for playback completion
+	done := make(chan struct{})
+
+	speaker.Play(beep.Seq(chime, beep.Callback(func() {
+		close(done)
+	})))
+
+	// Block until playback finishes
+	<-done
+	return nil
+}
+
 func (s *SoundNotifier) Close() error {
 	speaker.Close()
 	return nil